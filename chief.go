@@ -2,12 +2,10 @@ package uwe
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 	"time"
 
 	"github.com/lancer-kit/uwe/v3/socket"
@@ -17,45 +15,104 @@ import (
 // Chief is a supervisor that can be placed at the top  of the go app's execution stack,
 // it is blocked until SIGTERM is intercepted, and then it shut down all workers gracefully.
 // Also, `Chief` can be used as a child supervisor inside the `Worker`,
-// which is launched by `Chief` at the top-level.
+// which is launched by `Chief` at the top-level, see `NewSupervisorWorker`.
 type Chief interface {
 	// AddWorker registers the worker in the pool.
 	AddWorker(WorkerName, Worker, ...WorkerOpts) Chief
 	AddWorkerAndLaunch(WorkerName, Worker, ...WorkerOpts) Chief
+	// AddWorkerGroup sets the `SupervisionStrategy` for the group of workers.
+	// Workers are added to the group by passing the `GroupName` as a worker option.
+	AddWorkerGroup(GroupName, SupervisionStrategy) Chief
+	// StopWorker stops the running worker and waits until it finishes.
+	StopWorker(WorkerName) error
+	// StartWorker launches the stopped worker again.
+	StartWorker(WorkerName) error
+	// RestartWorker interrupts the current run of the worker and waits until it finishes,
+	// then the worker is started again. If `reInit` is true, the worker is re-initialized before the start.
+	RestartWorker(name WorkerName, reInit bool) error
+	// RemoveWorker stops the worker, if it is running, and removes it from the pool.
+	// The worker can not be removed while other workers depend on it.
+	RemoveWorker(WorkerName) error
+	// SetConfigLoader sets the loader of the config which is passed to the `Reloadable` workers on reload.
+	SetConfigLoader(ConfigLoader) Chief
+	// Reload loads the new config and applies it to the running workers, same as the SIGHUP signal.
+	// `Reloadable` workers receive the config, workers with the `ReInitOnReload` option
+	// are restarted with the re-initialization.
+	Reload() (ReloadResult, error)
 	// GetWorkersStates returns the current state of all registered workers.
 	GetWorkersStates() map[WorkerName]sam.State
+	// GetWorkersInfo returns the runtime details of all registered workers:
+	// timestamps, restart and panic counters, the last error.
+	GetWorkersInfo() map[WorkerName]WorkerInfo
+	// GetMailboxDepths returns the number of messages waiting for the delivery to each worker.
+	// It returns nil if the `IMQBroker` does not implement the `MailboxStats`.
+	GetMailboxDepths() map[WorkerName]int
+	// GetDroppedMessages returns the number of messages dropped for each worker.
+	// It returns nil if the `IMQBroker` does not implement the `DropStats`.
+	GetDroppedMessages() map[WorkerName]uint64
+	// AddObserver registers the `Observer` of the workers lifecycle and the IMQ traffic.
+	// The IMQ traffic is observed only if the `IMQBroker` implements the `ObservableBroker`.
+	AddObserver(Observer) Chief
+	// WaitReady blocks until the workers with passed names, or all workers if no names passed,
+	// signal the readiness with the `SignalReady(ctx)`, or until the context is done.
+	WaitReady(ctx context.Context, names ...WorkerName) error
 	// EnableServiceSocket initializes `net.Socket` server for internal management purposes.
-	// By default, includes two actions:
+	// By default, includes five actions:
 	// 	- "status" is a healthcheck-like, because it returns status of all workers;
+	// 	- "ready" is a readiness probe, it checks that all or requested workers are ready;
+	// 	- "reload" triggers the reload of the `Chief`, same as the SIGHUP signal;
+	// 	- "dead_letters" returns the last messages which were not delivered;
 	// 	- "ping" is a simple command that returns the "pong" message.
 	// The user can provide his own list of actions with handler closures.
 	EnableServiceSocket(app AppInfo, actions ...socket.Action) Chief
 	// Event returns the channel with internal Events.
-	// > ATTENTION:
-	//   `Event() <-chan Event` and `SetEventHandler(EventHandler)`
-	// are mutually exclusive, but one of them must be used!
+	//
+	// Deprecated: use `Subscribe`, it is the same.
 	Event() <-chan Event
 	// SetEventHandler adds a callback that processes the `Chief`
 	// internal events and can log them or do something else.
-	// > ATTENTION:
-	//   `Event() <-chan Event` and `SetEventHandler(EventHandler)`
-	// are mutually exclusive, but one of them must be used!
+	//
+	// Deprecated: use `AddEventHandler`, it is the same.
 	SetEventHandler(EventHandler) Chief
+	// AddEventHandler adds a callback that processes the `Chief`
+	// internal events and can log them or do something else.
+	// Any number of handlers can be added, they are called one by one in a separate goroutine.
+	AddEventHandler(EventHandler) Chief
+	// Subscribe returns a new channel with the `Chief` internal events.
+	// Any number of subscriptions can be used, each receives all events.
+	// The subscriber must read the channel until the `Unsubscribe` call.
+	Subscribe() <-chan Event
+	// Unsubscribe cancels the subscription and closes its channel.
+	Unsubscribe(<-chan Event)
+	// SetEventQueue replaces the size of the event queue and the `OverflowPolicy`.
+	// By default, the queue size is the `DefaultEventQueueSize` and the policy is `OverflowBlock`.
+	// It must be called before the `Chief` and workers start to emit events.
+	SetEventQueue(size int, policy OverflowPolicy) Chief
 	// SetContext replaces the default context with the provided one.
 	// It can be used to deliver some values inside `(Worker) .Run (ctx Context)`.
 	SetContext(context.Context) Chief
 	// SetLocker sets a custom `Locker`, if it is not set,
-	// the default `Locker` will be used, which expects SIGTERM or SIGINT system signals.
+	// the `Chief` runs until the shutdown signal handled by the `SignalHandler`.
 	SetLocker(Locker) Chief
+	// SetSignalHandler sets the handling of the OS signals. If neither `Locker` nor `SignalHandler` is set,
+	// the `DefaultSignalHandler` is used. The custom `SignalHandler` is active even with the custom `Locker`.
+	SetSignalHandler(SignalHandler) Chief
 	// SetShutdown sets `Shutdown` callback.
 	SetShutdown(Shutdown) Chief
 	// SetForceStopTimeout replaces the `DefaultForceStopTimeout`.
 	// ForceStopTimeout is the duration before
 	// the worker will be killed if it wouldn't finish Run after the stop signal.
+	// It can be overridden for the particular worker with the `StopTimeout` option.
 	SetForceStopTimeout(time.Duration) Chief
 	// UseCustomIMQBroker sets non-standard implementation
 	// of the IMQBroker to replace default one.
 	UseCustomIMQBroker(IMQBroker) Chief
+	// SetDeadLetterConfig sets the handling of the messages which can not be delivered.
+	// It is applied only if the `IMQBroker` implements the `DeadLetterBroker`.
+	SetDeadLetterConfig(DeadLetterConfig) Chief
+	// GetDeadLetters returns the last messages which were not delivered, the oldest first.
+	// It returns nil if the `IMQBroker` does not implement the `DeadLetterBroker`.
+	GetDeadLetters() []DeadLetter
 	// UseNopIMQBroker replaces default IMQ Broker by empty stub.
 	// NOP stands for no-operations.
 	UseNopIMQBroker() Chief
@@ -64,6 +121,10 @@ type Chief interface {
 	// if enabled, starts the workers in separate routines
 	// and waits for the end of lock produced by the locker function.
 	Run()
+	// RunE is the same as `Run`, but returns the reason why the `Chief` stopped.
+	// It returns nil if the `Chief` was stopped by the `Locker` or the `Shutdown` call,
+	// otherwise the `*StopError` is returned. Use `ExitCode` to get the process exit code.
+	RunE() error
 	// Shutdown sends stop signal to all child goroutines
 	// by triggering of the `context.CancelFunc()` and
 	// executes `Shutdown` callback.
@@ -89,54 +150,100 @@ type (
 // DefaultForceStopTimeout is a timeout for killing all workers.
 const DefaultForceStopTimeout = 45 * time.Second
 
+// ErrChiefNotRunning is returned when the action requires the running `Chief`.
+var ErrChiefNotRunning = errors.New("chief is not running")
+
 type chief struct {
-	ctx    context.Context
-	cancel context.CancelFunc
+	parentCtx context.Context
+	ctx       context.Context
+	cancel    context.CancelFunc
+	ran       bool
 
 	forceStopTimeout time.Duration
 	locker           Locker
+	signalHandler    *SignalHandler
 	shutdown         Shutdown
+	shutdownOnce     sync.Once
+	stopCauseMutex   sync.Mutex
+	stopCause        *StopError
 	wPool            *workerPool
 
 	rtWorkersLaunched bool
 	rtWorkersMutex    sync.Mutex
-	rtWorkersWG       sync.WaitGroup
+	rtServicesWG      sync.WaitGroup
 	rtWorkersCtx      context.Context
 
-	eventMutexLocked bool
-	eventMutex       sync.Mutex
-	eventChan        chan Event
-	eventHandler     EventHandler
+	events *eventDispatcher
 
-	broker IMQBroker
-	sw     *socket.Server
+	// reloadMutex serializes the reloads and guards the configLoader.
+	reloadMutex  sync.Mutex
+	configLoader ConfigLoader
+
+	broker      IMQBroker
+	deadLetters DeadLetterConfig
+	sw          *socket.Server
 }
 
 // NewChief returns new instance of standard `Chief` implementation.
 func NewChief() Chief {
 	c := &chief{
-		eventChan:        make(chan Event),
+		events:           newEventDispatcher(DefaultEventQueueSize, OverflowBlock),
 		forceStopTimeout: DefaultForceStopTimeout,
-		wPool: &workerPool{
-			workers: make(map[WorkerName]*workerRO),
-		},
+		wPool:            newWorkerPool(),
 	}
 
-	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.wPool.escalate = c.escalate
+	c.parentCtx = context.Background()
+	c.ctx, c.cancel = context.WithCancel(c.parentCtx)
 	return c
 }
 
 // EnableServiceSocket initializes `net.Socket` server for internal management purposes.
-// By default, includes two actions:
+// By default, includes five actions:
 //   - "status" is a command useful for health-checks, because it returns status of all workers;
+//   - "ready" is a command useful for readiness probes, it checks that all or requested workers are ready;
+//   - "reload" is a command that triggers the reload of the `Chief`, same as the SIGHUP signal;
+//   - "dead_letters" is a command that returns the last messages which were not delivered;
 //   - "ping" is a simple command that returns the "pong" message.
 //
 // The user can provide his own list of actions with handler closures.
 func (c *chief) EnableServiceSocket(app AppInfo, actions ...socket.Action) Chief {
 	statusAction := socket.Action{Name: StatusAction,
 		Handler: func(_ socket.Request) socket.Response {
-			return socket.NewResponse(socket.StatusOk,
-				StateInfo{App: app, Workers: c.wPool.getWorkersStates()}, "")
+			return socket.NewResponse(socket.StatusOk, c.stateInfo(app), "")
+		},
+	}
+
+	readyAction := socket.Action{Name: ReadyAction,
+		Handler: func(req socket.Request) socket.Response {
+			var names []WorkerName
+			if len(req.Args) > 0 {
+				if err := json.Unmarshal(req.Args, &names); err != nil {
+					return socket.NewResponse(socket.StatusErr, nil, "invalid args: "+err.Error())
+				}
+			}
+
+			info := c.readyInfo(names)
+			if !info.Ready {
+				return socket.NewResponse(socket.StatusErr, info, "not_ready")
+			}
+			return socket.NewResponse(socket.StatusOk, info, "")
+		},
+	}
+
+	reloadAction := socket.Action{Name: ReloadAction,
+		Handler: func(_ socket.Request) socket.Response {
+			result, err := c.Reload()
+			if err != nil {
+				return socket.NewResponse(socket.StatusErr, result, err.Error())
+			}
+			return socket.NewResponse(socket.StatusOk, result, "")
+		},
+	}
+
+	deadLettersAction := socket.Action{Name: DeadLettersAction,
+		Handler: func(_ socket.Request) socket.Response {
+			return socket.NewResponse(socket.StatusOk, c.GetDeadLetters(), "")
 		},
 	}
 
@@ -146,7 +253,7 @@ func (c *chief) EnableServiceSocket(app AppInfo, actions ...socket.Action) Chief
 		},
 	}
 
-	actions = append(actions, statusAction, pingAction)
+	actions = append(actions, statusAction, readyAction, reloadAction, deadLettersAction, pingAction)
 	c.sw = socket.NewServer(app.SocketName(), actions...)
 	return c
 }
@@ -154,7 +261,7 @@ func (c *chief) EnableServiceSocket(app AppInfo, actions ...socket.Action) Chief
 // AddWorker registers the worker in the pool.
 func (c *chief) AddWorker(name WorkerName, worker Worker, opts ...WorkerOpts) Chief {
 	if err := c.wPool.setWorker(name, worker, opts); err != nil {
-		c.eventChan <- ErrorEvent(err.Error()).SetWorker(name)
+		c.emit(ErrorEventFrom(err).SetWorker(name))
 	}
 
 	return c
@@ -162,10 +269,15 @@ func (c *chief) AddWorker(name WorkerName, worker Worker, opts ...WorkerOpts) Ch
 
 func (c *chief) AddWorkerAndLaunch(name WorkerName, worker Worker, opts ...WorkerOpts) Chief {
 	if err := c.wPool.setWorker(name, worker, opts); err != nil {
-		c.eventChan <- ErrorEvent(err.Error()).SetWorker(name)
+		c.emit(ErrorEventFrom(err).SetWorker(name))
 	}
 
 	if c.rtWorkersLaunched {
+		if err := c.wPool.validateDependencies(); err != nil {
+			c.emit(ErrorEventFrom(err).SetWorker(name))
+			return c
+		}
+
 		c.rtWorkersMutex.Lock()
 		c.launchWorker(name)
 		c.rtWorkersMutex.Unlock()
@@ -174,27 +286,343 @@ func (c *chief) AddWorkerAndLaunch(name WorkerName, worker Worker, opts ...Worke
 	return c
 }
 
+// AddWorkerGroup sets the `SupervisionStrategy` for the group of workers.
+// Workers are added to the group by passing the `GroupName` as a worker option.
+func (c *chief) AddWorkerGroup(name GroupName, strategy SupervisionStrategy) Chief {
+	c.wPool.setGroup(name, strategy)
+	return c
+}
+
+// StopWorker stops the running worker and waits until it finishes.
+func (c *chief) StopWorker(name WorkerName) error {
+	err := c.stopWorker(name)
+	c.reportControl(name, "stop", err)
+	return err
+}
+
+// StartWorker launches the stopped worker again.
+func (c *chief) StartWorker(name WorkerName) error {
+	err := c.startWorker(name)
+	c.reportControl(name, "start", err)
+	return err
+}
+
+// RestartWorker interrupts the current run of the worker and waits until it finishes,
+// then the worker is started again. If `reInit` is true, the worker is re-initialized before the start.
+func (c *chief) RestartWorker(name WorkerName, reInit bool) error {
+	err := c.restartWorker(name, reInit)
+	c.reportControl(name, "restart", err)
+	return err
+}
+
+// RemoveWorker stops the worker, if it is running, and removes it from the pool.
+// The worker can not be removed while other workers depend on it.
+func (c *chief) RemoveWorker(name WorkerName) error {
+	err := c.removeWorker(name)
+	c.reportControl(name, "remove", err)
+	return err
+}
+
+func (c *chief) stopWorker(name WorkerName) error {
+	launched, err := c.wPool.isLaunched(name)
+	if err != nil {
+		return err
+	}
+	if !launched {
+		return fmt.Errorf("%s: %w", name, ErrWorkerNotRunning)
+	}
+
+	return c.stopWorkers(time.Time{}, name)
+}
+
+func (c *chief) startWorker(name WorkerName) error {
+	launched, err := c.wPool.isLaunched(name)
+	if err != nil {
+		return err
+	}
+	if launched {
+		return fmt.Errorf("%s: %w", name, ErrWorkerRunning)
+	}
+
+	c.rtWorkersMutex.Lock()
+	defer c.rtWorkersMutex.Unlock()
+
+	if !c.rtWorkersLaunched || stopInitiated(c.ctx) {
+		return ErrChiefNotRunning
+	}
+
+	if err = c.wPool.resetWorker(name); err != nil {
+		return err
+	}
+
+	c.launchWorker(name)
+	return nil
+}
+
+func (c *chief) restartWorker(name WorkerName, reInit bool) error {
+	run, err := c.wPool.interruptRun(name, reInit, "restart on request")
+	if err != nil {
+		return err
+	}
+
+	timeout := c.wPool.stopTimeout(name, c.forceStopTimeout)
+	select {
+	case <-run.done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("%s: worker did not stop within %s", name, timeout)
+	}
+}
+
+func (c *chief) removeWorker(name WorkerName) error {
+	if dependents := c.wPool.dependents(name); len(dependents) > 0 {
+		return fmt.Errorf("%s: worker is required by %v", name, dependents)
+	}
+
+	// the worker can not be started again between the stop and the removal
+	launched, err := c.wPool.beginRemove(name)
+	if err != nil {
+		return err
+	}
+
+	if launched {
+		if err = c.stopWorkers(time.Time{}, name); err != nil {
+			c.wPool.cancelRemove(name)
+			return err
+		}
+	}
+
+	c.wPool.removeWorker(name)
+	if broker, ok := c.broker.(RemovableBroker); ok {
+		broker.RemoveWorker(name)
+	}
+
+	return nil
+}
+
+// stopWorkers stops the workers and emits an event for each worker that did not stop in time.
+// The workers are awaited no longer than the `deadline`, if it is not zero.
+func (c *chief) stopWorkers(deadline time.Time, names ...WorkerName) error {
+	stuck := c.wPool.stopWorkers(names, c.forceStopTimeout, deadline)
+	for _, w := range stuck {
+		c.emit(Event{
+			Level: LvlError, Kind: KindWorkerStuck, Worker: w.name,
+			Message: "Worker did not stop in time",
+			Fields: map[string]interface{}{
+				"timeout": w.timeout.String(),
+				"elapsed": w.elapsed.String(),
+				"stack":   w.stack,
+			},
+		})
+	}
+
+	if len(stuck) > 0 {
+		return fmt.Errorf("%s: worker did not stop within %s", stuck[0].name, stuck[0].timeout)
+	}
+	return nil
+}
+
+// reportControl emits the event with the outcome of the worker control action.
+func (c *chief) reportControl(name WorkerName, action string, err error) {
+	if err != nil {
+		c.emit(Event{
+			Level: LvlError, Kind: KindWorkerControl, Worker: name,
+			Message: "Worker control action failed",
+			Fields:  map[string]interface{}{"action": action, "error": err.Error()},
+			Err:     err,
+		})
+		return
+	}
+
+	c.emit(Event{
+		Level: LvlInfo, Kind: KindWorkerControl, Worker: name,
+		Message: "Worker control action completed",
+		Fields:  map[string]interface{}{"action": action},
+	})
+}
+
 // GetWorkersStates returns the current state of all registered workers.
 func (c *chief) GetWorkersStates() map[WorkerName]sam.State {
 	return c.wPool.getWorkersStates()
 }
 
+// GetWorkersInfo returns the runtime details of all registered workers.
+func (c *chief) GetWorkersInfo() map[WorkerName]WorkerInfo {
+	return c.wPool.getWorkersInfo()
+}
+
+// GetMailboxDepths returns the number of messages waiting for the delivery to each worker.
+func (c *chief) GetMailboxDepths() map[WorkerName]int {
+	c.rtWorkersMutex.Lock()
+	broker := c.broker
+	c.rtWorkersMutex.Unlock()
+
+	if stats, ok := broker.(MailboxStats); ok {
+		return stats.MailboxDepths()
+	}
+	return nil
+}
+
+// GetDroppedMessages returns the number of messages dropped for each worker.
+func (c *chief) GetDroppedMessages() map[WorkerName]uint64 {
+	c.rtWorkersMutex.Lock()
+	broker := c.broker
+	c.rtWorkersMutex.Unlock()
+
+	if stats, ok := broker.(DropStats); ok {
+		return stats.DroppedMessages()
+	}
+	return nil
+}
+
+// SetDeadLetterConfig sets the handling of the messages which can not be delivered.
+func (c *chief) SetDeadLetterConfig(config DeadLetterConfig) Chief {
+	c.rtWorkersMutex.Lock()
+	defer c.rtWorkersMutex.Unlock()
+
+	c.deadLetters = config
+	if broker, ok := c.broker.(DeadLetterBroker); ok && c.rtWorkersLaunched {
+		broker.SetDeadLetterConfig(c.deadLetterConfig())
+	}
+	return c
+}
+
+// GetDeadLetters returns the last messages which were not delivered, the oldest first.
+func (c *chief) GetDeadLetters() []DeadLetter {
+	c.rtWorkersMutex.Lock()
+	broker := c.broker
+	c.rtWorkersMutex.Unlock()
+
+	if dlb, ok := broker.(DeadLetterBroker); ok {
+		return dlb.DeadLetters()
+	}
+	return nil
+}
+
+// deadLetterConfig returns the config for the broker, which emits
+// the `KindMessageDeadLettered` event before the call of the user handler.
+func (c *chief) deadLetterConfig() DeadLetterConfig {
+	config := c.deadLetters
+	handler := config.Handler
+	config.Handler = func(letter DeadLetter) {
+		fields := map[string]interface{}{
+			"reason": string(letter.Reason),
+			"sender": string(letter.Message.Sender),
+			"target": string(letter.Message.Target),
+			"kind":   int(letter.Message.Kind),
+		}
+		if letter.Message.Topic != "" {
+			fields["topic"] = letter.Message.Topic
+		}
+
+		c.emit(Event{
+			Level: LvlWarn, Kind: KindMessageDeadLettered, Worker: letter.Receiver,
+			Message: "Message was not delivered",
+			Fields:  fields,
+		})
+
+		if handler != nil {
+			handler(letter)
+		}
+	}
+	return config
+}
+
+// AddObserver registers the `Observer` of the workers lifecycle and the IMQ traffic.
+func (c *chief) AddObserver(observer Observer) Chief {
+	c.wPool.observer.add(observer)
+	return c
+}
+
+// WaitReady blocks until the workers with passed names, or all workers if no names passed,
+// signal the readiness with the `SignalReady(ctx)`, or until the context is done.
+func (c *chief) WaitReady(ctx context.Context, names ...WorkerName) error {
+	if len(names) == 0 {
+		names = c.wPool.workersList()
+	}
+
+	return c.wPool.waitForStates(ctx, names,
+		func(state sam.State) bool { return state == WStateReady }, nil)
+}
+
+// readyInfo returns the readiness of the workers with passed names, or all workers if no names passed.
+func (c *chief) readyInfo(names []WorkerName) ReadyInfo {
+	states := c.wPool.getWorkersStates()
+	if len(names) == 0 {
+		for name := range states {
+			names = append(names, name)
+		}
+	}
+
+	info := ReadyInfo{Ready: true, Workers: map[WorkerName]bool{}}
+	for _, name := range names {
+		ready := states[name] == WStateReady
+		info.Workers[name] = ready
+		info.Ready = info.Ready && ready
+	}
+
+	return info
+}
+
+// signalReady moves the worker to the `WStateReady` state.
+func (c *chief) signalReady(name WorkerName) {
+	ok, err := c.wPool.readyWorker(name)
+	if err != nil {
+		c.emit(ErrorEventFrom(err).SetWorker(name))
+		return
+	}
+
+	if ok {
+		c.emit(Event{Level: LvlInfo, Kind: KindWorkerReady, Worker: name, Message: "Worker is ready"})
+	}
+}
+
 // SetEventHandler adds a callback that processes the `Chief`
 // internal events and can log them or do something else.
+//
+// Deprecated: use `AddEventHandler`, it is the same.
 func (c *chief) SetEventHandler(handler EventHandler) Chief {
-	c.eventHandler = handler
+	return c.AddEventHandler(handler)
+}
+
+// AddEventHandler adds a callback that processes the `Chief`
+// internal events and can log them or do something else.
+func (c *chief) AddEventHandler(handler EventHandler) Chief {
+	c.events.addHandler(handler)
+	return c
+}
+
+// Subscribe returns a new channel with the `Chief` internal events.
+func (c *chief) Subscribe() <-chan Event {
+	return c.events.subscribe()
+}
+
+// Unsubscribe cancels the subscription and closes its channel.
+func (c *chief) Unsubscribe(sub <-chan Event) {
+	c.events.unsubscribe(sub)
+}
+
+// SetEventQueue replaces the size of the event queue and the `OverflowPolicy`.
+func (c *chief) SetEventQueue(size int, policy OverflowPolicy) Chief {
+	c.events.setQueue(size, policy)
 	return c
 }
 
+// emit sends the event to the handlers and subscribers.
+func (c *chief) emit(event Event) {
+	c.events.emit(event)
+}
+
 // SetContext replaces the default context with the provided one.
 // It can be used to deliver some values inside `(Worker).Run(ctx Context)`.
 func (c *chief) SetContext(ctx context.Context) Chief {
-	c.ctx = ctx
+	c.parentCtx = ctx
+	c.ctx, c.cancel = context.WithCancel(ctx)
 	return c
 }
 
 // SetLocker sets a custom `Locker`, if it is not set,
-// the default `Locker` will be used, which expects SIGTERM or SIGINT system signals.
+// the `Chief` runs until the shutdown signal handled by the `SignalHandler`.
 func (c *chief) SetLocker(locker Locker) Chief {
 	c.locker = locker
 	return c
@@ -223,13 +651,10 @@ func (c *chief) SetForceStopTimeout(forceStopTimeout time.Duration) Chief {
 }
 
 // Event returns the channel with internal Events.
+//
+// Deprecated: use `Subscribe`, it is the same.
 func (c *chief) Event() <-chan Event {
-	if c.eventHandler != nil {
-		return nil
-	}
-	c.eventMutexLocked = true
-	c.eventMutex.Lock()
-	return c.eventChan
+	return c.Subscribe()
 }
 
 // Run is the main entry point into the `Chief` run loop.
@@ -237,92 +662,166 @@ func (c *chief) Event() <-chan Event {
 // if enabled, starts the workers in separate goroutines
 // and waits for the end of lock produced by the locker function.
 func (c *chief) Run() {
-	if c.locker == nil {
-		c.locker = waitForSignal
+	// the `Chief` can be run again after the stop,
+	// for example as a child supervisor restarted by the parent.
+	if c.ran {
+		c.shutdownOnce = sync.Once{}
+		c.ctx, c.cancel = context.WithCancel(c.parentCtx)
 	}
+	c.ran = true
 
-	if c.eventHandler != nil {
-		stop := make(chan struct{})
-		defer func() {
-			stop <- struct{}{}
-		}()
-		go c.handleEvents(stop)
+	// all events emitted during the run are delivered before the return.
+	defer c.events.close()
+
+	c.stopCauseMutex.Lock()
+	c.stopCause = nil
+	c.stopCauseMutex.Unlock()
+
+	signals := c.listenSignals()
+	defer signals.close()
+
+	locker := c.locker
+	if locker == nil {
+		// the shutdown signal cancels the context
+		ctx := c.ctx
+		locker = func() { <-ctx.Done() }
 	}
 
-	c.run()
+	c.run(locker)
+}
+
+// RunE is the same as `Run`, but returns the reason why the `Chief` stopped.
+// It returns nil if the `Chief` was stopped by the `Locker` or the `Shutdown` call,
+// otherwise the `*StopError` is returned. Use `ExitCode` to get the process exit code.
+func (c *chief) RunE() error {
+	c.Run()
+
+	cause := c.getStopCause()
+	if cause == nil || cause.Reason == StopBySignal {
+		return nil
+	}
+	return cause
 }
 
 // Shutdown sends stop signal to all child goroutines
 // by triggering of the `context.CancelFunc()`
 // and executes `Shutdown` callback.
 func (c *chief) Shutdown() {
-	c.cancel()
-	if c.eventMutexLocked {
-		c.eventMutex.Unlock()
-	}
+	c.shutdownOnce.Do(func() {
+		c.cancel()
+
+		if c.shutdown != nil {
+			c.shutdown()
+		}
+	})
+}
 
-	if c.shutdown != nil {
-		c.shutdown()
+// escalate shuts down the `Chief` because of the failed worker.
+func (c *chief) escalate(reason StopReason, name WorkerName, err error) {
+	c.emit(Event{
+		Level: LvlFatal, Kind: KindChiefStopping, Worker: name,
+		Message: "Chief will be stopped due to a failed worker",
+		Fields:  map[string]interface{}{"error": err.Error(), "reason": reason},
+		Err:     err,
+	})
+
+	c.setStopCause(&StopError{Reason: reason, Worker: name, Err: err})
+	c.Shutdown()
+}
+
+// setStopCause records the first cause of the `Chief` stop.
+func (c *chief) setStopCause(cause *StopError) {
+	c.stopCauseMutex.Lock()
+	defer c.stopCauseMutex.Unlock()
+
+	if c.stopCause == nil {
+		c.stopCause = cause
 	}
 }
 
-func (c *chief) run() {
-	lockerDone := make(chan struct{})
+// getStopCause returns the recorded cause of the `Chief` stop.
+func (c *chief) getStopCause() *StopError {
+	c.stopCauseMutex.Lock()
+	defer c.stopCauseMutex.Unlock()
+
+	return c.stopCause
+}
+
+func (c *chief) run(locker Locker) {
+	lockerDone := make(chan struct{}, 1)
 	go func() {
-		c.locker()
-		c.Shutdown()
+		locker()
 		lockerDone <- struct{}{}
 	}()
 
-	poolStopped := make(chan struct{})
+	poolStopped := make(chan struct{}, 1)
 	go func() {
 		err := c.runPool()
 		if err != nil {
-			c.eventChan <- ErrorEvent(err.Error())
-			lockerDone <- struct{}{}
+			c.emit(ErrorEventFrom(err))
+
+			var stopErr *StopError
+			if !errors.As(err, &stopErr) {
+				stopErr = &StopError{Reason: StopByInitFailure, Err: err}
+			}
+			c.setStopCause(stopErr)
 		}
 		poolStopped <- struct{}{}
 	}()
 
-	<-lockerDone
-
 	select {
+	case <-lockerDone:
+		c.setStopCause(&StopError{Reason: StopBySignal})
+		c.Shutdown()
 	case <-poolStopped:
-		return
-	case <-time.NewTimer(c.forceStopTimeout).C:
-		c.eventChan <- ErrorEvent("graceful shutdown failed")
+		// pool can stop before the locker release
+		// if start failed or shutdown was initiated from the inside.
+		c.Shutdown()
 		return
 	}
-}
-
-func (c *chief) handleEvents(stop <-chan struct{}) {
-	c.eventMutexLocked = true
-	c.eventMutex.Lock()
 
-	for {
-		select {
-		case event := <-c.eventChan:
-			c.eventHandler(event)
-		case <-stop:
-			return
-		}
-	}
+	// the pool shutdown is limited by the `forceStopTimeout`.
+	<-poolStopped
 }
 
 func (c *chief) runPool() error {
-	c.rtWorkersWG = sync.WaitGroup{}
+	c.rtServicesWG = sync.WaitGroup{}
 
 	var runCount int
-	ctx, cancel := context.WithCancel(c.ctx)
+	// workers context is detached from the `Chief` context
+	// to stop workers one by one in the order of their dependencies.
+	ctx, cancel := context.WithCancel(detachedContext{parent: c.ctx})
+	c.rtWorkersMutex.Lock()
 	c.rtWorkersCtx = ctx
 	c.rtWorkersLaunched = true
+	c.rtWorkersMutex.Unlock()
 
+	if err := c.wPool.validateDependencies(); err != nil {
+		cancel()
+		return fmt.Errorf("unable to start: %w", err)
+	}
+
+	if err := c.wPool.resetWorkers(); err != nil {
+		cancel()
+		return fmt.Errorf("unable to start: %w", err)
+	}
+
+	c.rtWorkersMutex.Lock()
 	if c.broker == nil {
 		c.broker = NewBroker(len(c.wPool.workers) * 4)
 	}
+	if broker, ok := c.broker.(DeadLetterBroker); ok {
+		broker.SetDeadLetterConfig(c.deadLetterConfig())
+	}
+	c.rtWorkersMutex.Unlock()
+
+	if broker, ok := c.broker.(ObservableBroker); ok {
+		broker.SetObserver(c.wPool.observer)
+	}
 	if err := c.broker.Init(); err != nil {
 		cancel()
-		return fmt.Errorf("unable to init imq broker: %w", err)
+		return &StopError{Reason: StopByInitFailure,
+			Err: fmt.Errorf("unable to init imq broker: %w", err)}
 	}
 
 	for _, name := range c.wPool.workersList() {
@@ -332,23 +831,23 @@ func (c *chief) runPool() error {
 
 	if runCount == 0 {
 		cancel()
-		return errors.New("unable to start: there is no initialized workers")
+		return &StopError{Reason: StopNoWorkers,
+			Err: errors.New("unable to start: there is no initialized workers")}
 	}
 
-	c.rtWorkersWG.Add(1)
+	c.rtServicesWG.Add(1)
 	go func() {
-		defer c.rtWorkersWG.Done()
+		defer c.rtServicesWG.Done()
 		c.broker.Serve(ctx)
 	}()
 
 	if c.sw != nil {
-		c.rtWorkersWG.Add(1)
+		c.rtServicesWG.Add(1)
 		go func() {
-			defer c.rtWorkersWG.Done()
+			defer c.rtServicesWG.Done()
 			if err := c.sw.Serve(ctx); err != nil {
-				c.eventChan <- ErrorEvent(
-					fmt.Sprintf("failed to run listener: %s", err)).
-					SetWorker("internal_socket_listener")
+				c.emit(ErrorEventFrom(fmt.Errorf("failed to run listener: %w", err)).
+					SetWorker("internal_socket_listener"))
 			}
 
 		}()
@@ -356,29 +855,110 @@ func (c *chief) runPool() error {
 
 	<-c.ctx.Done()
 
+	// the whole ordered shutdown shares the single `forceStopTimeout`,
+	// workers are awaited by the stopWorkers within their stop timeouts, but not after the deadline,
+	// the wait group covers only the broker and the socket server.
+	deadline := time.Now().Add(c.forceStopTimeout)
+	var failed bool
+	for _, names := range c.wPool.shutdownOrder() {
+		if err := c.stopWorkers(deadline, names...); err != nil {
+			failed = true
+		}
+	}
+
 	cancel()
-	c.rtWorkersWG.Wait()
+	if !waitUntil(&c.rtServicesWG, deadline) || failed {
+		c.emit(ErrorEvent("graceful shutdown failed"))
+	}
 
 	return nil
 }
 
+// waitUntil waits for the wait group until the deadline, it reports whether the wait group is done.
+func waitUntil(wg *sync.WaitGroup, deadline time.Time) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 func (c *chief) launchWorker(name WorkerName) {
-	c.rtWorkersWG.Add(1)
+	if broker, ok := c.broker.(MailboxConfigurer); ok {
+		if w := c.wPool.getWorker(name); w != nil && w.mailbox != nil {
+			broker.SetMailboxOption(name, *w.mailbox)
+		}
+	}
 	mailbox := c.broker.AddWorker(name)
-	go c.runWorker(NewContext(c.rtWorkersCtx, mailbox), name, c.rtWorkersWG.Done)
+
+	ctx, cancel := context.WithCancel(c.rtWorkersCtx)
+	done := make(chan struct{})
+	c.wPool.setRuntime(name, cancel, done)
+
+	env := &workerEnv{
+		name:      name,
+		emit:      c.childEventEmitter(name),
+		ready:     func() { c.signalReady(name) },
+		heartbeat: func() { c.wPool.heartbeat(name) },
+	}
+	go c.runWorker(newWorkerContext(ctx, mailbox, env), name, func() {
+		cancel()
+		close(done)
+	})
 }
 
 func (c *chief) runWorker(ctx Context, name WorkerName, doneCall func()) {
 	defer doneCall()
+	c.wPool.setGoroutine(name, goroutineID())
 
-	err := c.wPool.runWorkerExec(ctx, c.eventChan, name)
+	err := c.wPool.runWorkerExec(ctx, c.emit, name)
 	if err != nil {
-		c.eventChan <- ErrorEvent(err.Error()).SetWorker(name)
+		c.emit(ErrorEventFrom(err).SetWorker(name))
+	}
+
+	if broker, ok := c.broker.(RequestBroker); ok {
+		broker.WorkerStopped(name)
 	}
 }
 
-func waitForSignal() {
-	gracefulStop := make(chan os.Signal, 1)
-	signal.Notify(gracefulStop, syscall.SIGTERM, syscall.SIGINT)
-	<-gracefulStop
+// childEventEmitter returns a function that delivers events from the worker to the `Chief` event stream.
+// Worker names of the child supervisor events are prefixed with the name of the worker.
+func (c *chief) childEventEmitter(name WorkerName) func(Event) {
+	return func(event Event) {
+		if event.Worker == "" {
+			event.Worker = name
+		} else {
+			event.Worker = name + "/" + event.Worker
+		}
+		c.emit(event)
+	}
+}
+
+// stateInfo returns the state of all workers including the nested child supervisors.
+func (c *chief) stateInfo(app AppInfo) StateInfo {
+	info := StateInfo{
+		App:     app,
+		Workers: c.wPool.getWorkersStates(),
+		Details: c.wPool.getWorkersInfo(),
+	}
+
+	for name, child := range c.wPool.getChildSupervisors() {
+		if info.Children == nil {
+			info.Children = map[WorkerName]*StateInfo{}
+		}
+		childInfo := child.stateInfo()
+		info.Children[name] = &childInfo
+	}
+
+	return info
 }
@@ -0,0 +1,965 @@
+package uwe
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sheb-gregor/sam"
+)
+
+type failingWorker struct {
+	runs int
+}
+
+func (w *failingWorker) Run(Context) error {
+	w.runs++
+	return errors.New("failed")
+}
+
+func runChief(t *testing.T, chief Chief, timeout time.Duration) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		chief.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Fatal("chief was not stopped in time")
+	}
+}
+
+func waitEvent(t *testing.T, events <-chan Event, kind EventKind) Event {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Kind == kind {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("%s event was not emitted", kind)
+			return Event{}
+		}
+	}
+}
+
+func TestChief_RestartLimitEscalation(t *testing.T) {
+	worker := &failingWorker{}
+	var fatal []Event
+
+	chief := NewChief()
+	chief.SetLocker(func() { select {} })
+	chief.SetEventHandler(func(e Event) {
+		if e.IsFatal() {
+			fatal = append(fatal, e)
+		}
+	})
+	chief.AddWorker("failing", worker, Restart,
+		RestartLimit{MaxRestarts: 2, Window: time.Minute, Escalate: true})
+
+	runChief(t, chief, 5*time.Second)
+
+	if worker.runs != 3 {
+		t.Errorf("worker runs(%d) != 3", worker.runs)
+	}
+	if len(fatal) == 0 {
+		t.Error("fatal event was not emitted")
+	}
+
+	states := chief.GetWorkersStates()
+	if states["failing"] != WStateFailed {
+		t.Errorf("worker state(%s) != %s", states["failing"], WStateFailed)
+	}
+}
+
+type blockingWorker struct {
+	inits int32
+	runs  int32
+	fails int32
+}
+
+func (w *blockingWorker) Init() error {
+	atomic.AddInt32(&w.inits, 1)
+	return nil
+}
+
+func (w *blockingWorker) Run(ctx Context) error {
+	atomic.AddInt32(&w.runs, 1)
+	if atomic.AddInt32(&w.fails, -1) >= 0 {
+		// give other workers time to start
+		time.Sleep(100 * time.Millisecond)
+		return errors.New("failed")
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func waitFor(cond func() bool) Locker {
+	return func() {
+		for !cond() {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestChief_GroupStrategies(t *testing.T) {
+	cases := []struct {
+		strategy      SupervisionStrategy
+		expectedRuns  [3]int32
+		expectedInits [3]int32
+	}{
+		{strategy: OneForOne, expectedRuns: [3]int32{1, 2, 1}, expectedInits: [3]int32{1, 1, 1}},
+		{strategy: OneForAll, expectedRuns: [3]int32{2, 2, 2}, expectedInits: [3]int32{2, 1, 2}},
+		{strategy: RestForOne, expectedRuns: [3]int32{1, 2, 2}, expectedInits: [3]int32{1, 1, 2}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.strategy.String(), func(t *testing.T) {
+			workers := [3]*blockingWorker{{}, {fails: 1}, {}}
+			started := func() bool {
+				for i, w := range workers {
+					if atomic.LoadInt32(&w.runs) < tc.expectedRuns[i] {
+						return false
+					}
+				}
+				return true
+			}
+
+			chief := NewChief()
+			chief.SetLocker(waitFor(started))
+			chief.SetEventHandler(func(Event) {})
+			chief.AddWorkerGroup("group", tc.strategy)
+			chief.AddWorker("first", workers[0], GroupName("group"))
+			chief.AddWorker("second", workers[1], Restart, GroupName("group"))
+			chief.AddWorker("third", workers[2], GroupName("group"))
+
+			runChief(t, chief, 5*time.Second)
+
+			for i, w := range workers {
+				if w.runs != tc.expectedRuns[i] {
+					t.Errorf("worker %d: runs(%d) != %d", i, w.runs, tc.expectedRuns[i])
+				}
+				if w.inits != tc.expectedInits[i] {
+					t.Errorf("worker %d: inits(%d) != %d", i, w.inits, tc.expectedInits[i])
+				}
+			}
+		})
+	}
+}
+
+type orderRecorder struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (r *orderRecorder) add(event string) {
+	r.mutex.Lock()
+	r.events = append(r.events, event)
+	r.mutex.Unlock()
+}
+
+func (r *orderRecorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.events)
+}
+
+type recordingWorker struct {
+	name     string
+	recorder *orderRecorder
+}
+
+func (w *recordingWorker) Init() error {
+	w.recorder.add("init:" + w.name)
+	return nil
+}
+
+func (w *recordingWorker) Run(ctx Context) error {
+	<-ctx.Done()
+	// slow down the stop to catch workers stopped out of order
+	time.Sleep(20 * time.Millisecond)
+	w.recorder.add("stop:" + w.name)
+	return nil
+}
+
+func TestChief_Dependencies(t *testing.T) {
+	recorder := &orderRecorder{}
+
+	chief := NewChief()
+	chief.SetLocker(waitFor(func() bool { return recorder.count() == 3 }))
+	chief.SetEventHandler(func(Event) {})
+	chief.AddWorker("consumer", &recordingWorker{name: "consumer", recorder: recorder}, DependsOn("api"))
+	chief.AddWorker("api", &recordingWorker{name: "api", recorder: recorder}, DependsOn("db"))
+	chief.AddWorker("db", &recordingWorker{name: "db", recorder: recorder})
+
+	runChief(t, chief, 5*time.Second)
+
+	expected := "init:db init:api init:consumer stop:consumer stop:api stop:db"
+	if got := strings.Join(recorder.events, " "); got != expected {
+		t.Errorf("order(%s) != %s", got, expected)
+	}
+}
+
+func TestChief_InvalidDependencies(t *testing.T) {
+	cases := map[string][]WorkerOpts{
+		"unknown worker(missing)": {DependsOn("missing")},
+		"cycle detected":          {DependsOn("second")},
+	}
+
+	for errMsg, opts := range cases {
+		var errs []string
+
+		chief := NewChief()
+		chief.SetLocker(func() { select {} })
+		chief.SetEventHandler(func(e Event) {
+			if e.IsError() {
+				errs = append(errs, e.Message)
+			}
+		})
+		chief.AddWorker("first", &recordingWorker{recorder: &orderRecorder{}}, opts...)
+		chief.AddWorker("second", &recordingWorker{recorder: &orderRecorder{}}, DependsOn("first"))
+
+		runChief(t, chief, time.Second)
+
+		if len(errs) != 1 || !strings.Contains(errs[0], errMsg) {
+			t.Errorf("expected error with %q, got %v", errMsg, errs)
+		}
+	}
+}
+
+func TestChief_ControlWorkers(t *testing.T) {
+	worker := &blockingWorker{}
+	isRunning := func() bool {
+		return atomic.LoadInt32(&worker.runs) > 0
+	}
+
+	var chief Chief
+	var errs []error
+	var stoppedState sam.State
+
+	chief = NewChief()
+	chief.SetEventHandler(func(Event) {})
+	chief.AddWorker("dummy", &blockingWorker{})
+	chief.AddWorker("controlled", worker)
+	chief.SetLocker(func() {
+		waitFor(isRunning)()
+		check := func(err error) { errs = append(errs, err) }
+
+		check(chief.StopWorker("controlled"))
+		stoppedState = chief.GetWorkersStates()["controlled"]
+		check(chief.StopWorker("controlled"))
+		check(chief.StartWorker("controlled"))
+		waitFor(func() bool { return atomic.LoadInt32(&worker.runs) == 2 })()
+		check(chief.RestartWorker("controlled", true))
+		waitFor(func() bool { return atomic.LoadInt32(&worker.runs) == 3 })()
+		check(chief.RemoveWorker("controlled"))
+	})
+
+	runChief(t, chief, 5*time.Second)
+
+	expectedErrs := []error{nil, ErrWorkerNotRunning, nil, nil, nil}
+	for i, err := range errs {
+		if !errors.Is(err, expectedErrs[i]) {
+			t.Errorf("action %d: error(%v) != %v", i, err, expectedErrs[i])
+		}
+	}
+
+	if stoppedState != WStateStopped {
+		t.Errorf("state(%s) != %s", stoppedState, WStateStopped)
+	}
+
+	if worker.inits != 3 {
+		t.Errorf("inits(%d) != 3", worker.inits)
+	}
+	if _, ok := chief.GetWorkersStates()["controlled"]; ok {
+		t.Error("worker was not removed")
+	}
+}
+
+// removingWorker finishes the run only after the release when the context is done.
+type removingWorker struct {
+	running  chan struct{}
+	stopping chan struct{}
+	release  chan struct{}
+}
+
+func (w *removingWorker) Init() error { return nil }
+
+func (w *removingWorker) Run(ctx Context) error {
+	close(w.running)
+	<-ctx.Done()
+	close(w.stopping)
+	<-w.release
+	return nil
+}
+
+func TestChief_RemoveWorkerInProgress(t *testing.T) {
+	worker := &removingWorker{
+		running:  make(chan struct{}),
+		stopping: make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+
+	var errs []error
+	chief := NewChief()
+	chief.SetEventHandler(func(Event) {})
+	chief.AddWorker("dummy", &blockingWorker{})
+	chief.AddWorker("removed", worker)
+	chief.SetLocker(func() {
+		<-worker.running
+
+		removed := make(chan error, 1)
+		go func() { removed <- chief.RemoveWorker("removed") }()
+		<-worker.stopping
+
+		errs = append(errs, chief.StartWorker("removed"), chief.RestartWorker("removed", false))
+		close(worker.release)
+		errs = append(errs, <-removed, chief.StartWorker("removed"))
+	})
+
+	runChief(t, chief, 5*time.Second)
+
+	expectedErrs := []error{ErrWorkerNotExist, ErrWorkerNotExist, nil, ErrWorkerNotExist}
+	for i, err := range errs {
+		if !errors.Is(err, expectedErrs[i]) {
+			t.Errorf("action %d: error(%v) != %v", i, err, expectedErrs[i])
+		}
+	}
+}
+
+type hungWorker struct {
+	release chan struct{}
+}
+
+func (w *hungWorker) Run(Context) error {
+	<-w.release
+	return nil
+}
+
+func TestChief_StuckWorkerOnShutdown(t *testing.T) {
+	stuck := &hungWorker{release: make(chan struct{})}
+	defer close(stuck.release)
+
+	var events []Event
+	var mutex sync.Mutex
+
+	chief := NewChief()
+	chief.SetLocker(func() { time.Sleep(50 * time.Millisecond) })
+	chief.SetEventHandler(func(e Event) {
+		mutex.Lock()
+		events = append(events, e)
+		mutex.Unlock()
+	})
+	chief.AddWorker("stuck", stuck, StopTimeout(50*time.Millisecond))
+	chief.AddWorker("dummy", &blockingWorker{})
+
+	runChief(t, chief, time.Second)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var found bool
+	for _, e := range events {
+		if e.Worker != "stuck" || e.Message != "Worker did not stop in time" {
+			continue
+		}
+
+		found = true
+		stack, _ := e.Fields["stack"].(string)
+		if !strings.Contains(stack, "(*hungWorker).Run") {
+			t.Errorf("stack does not contain the worker Run call:\n%s", stack)
+		}
+	}
+
+	if !found {
+		t.Error("event about the stuck worker was not emitted")
+	}
+}
+
+func TestChief_ShutdownDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	base := &contextKeeper{contexts: make(chan Context, 1)}
+	chief := NewChief()
+	chief.SetForceStopTimeout(200 * time.Millisecond)
+	chief.SetLocker(func() { time.Sleep(50 * time.Millisecond) })
+	chief.SetEventHandler(func(Event) {})
+	chief.AddWorker("base", base)
+	chief.AddWorker("middle", &hungWorker{release: release}, DependsOn("base"))
+	chief.AddWorker("top", &hungWorker{release: release}, DependsOn("middle"))
+
+	// two levels of the shutdown order have the stuck workers,
+	// but all of them are awaited within the single force stop timeout
+	// and the last level is stopped before the `Run` returns.
+	startedAt := time.Now()
+	runChief(t, chief, time.Second)
+	if elapsed := time.Since(startedAt); elapsed > 400*time.Millisecond {
+		t.Errorf("shutdown took %s, it must be limited by the force stop timeout", elapsed)
+	}
+	if ctx := <-base.contexts; ctx.Err() == nil {
+		t.Error("the last level of the shutdown order was not stopped")
+	}
+}
+
+// contextKeeper passes its context to the test.
+type contextKeeper struct {
+	contexts chan Context
+}
+
+func (w *contextKeeper) Init() error { return nil }
+
+func (w *contextKeeper) Run(ctx Context) error {
+	w.contexts <- ctx
+	<-ctx.Done()
+	return nil
+}
+
+func TestChief_ChildSupervisor(t *testing.T) {
+	inner := &blockingWorker{}
+	var events []Event
+	var mutex sync.Mutex
+
+	child := NewChief()
+	child.AddWorker("inner", inner)
+
+	parent := NewChief()
+	parent.SetEventHandler(func(e Event) {
+		mutex.Lock()
+		events = append(events, e)
+		mutex.Unlock()
+	})
+	parent.AddWorker("child", NewSupervisorWorker(child))
+
+	var info StateInfo
+	parent.SetLocker(func() {
+		waitFor(func() bool { return atomic.LoadInt32(&inner.runs) == 1 })()
+		info = parent.(*chief).stateInfo(AppInfo{})
+
+		if err := parent.RestartWorker("child", false); err != nil {
+			t.Errorf("unable to restart child: %s", err)
+		}
+		waitFor(func() bool { return atomic.LoadInt32(&inner.runs) == 2 })()
+	})
+
+	runChief(t, parent, 5*time.Second)
+
+	if info.Workers["child"] != WStateRun {
+		t.Errorf("child state(%s) != %s", info.Workers["child"], WStateRun)
+	}
+	if info.Children["child"] == nil || info.Children["child"].Workers["inner"] != WStateRun {
+		t.Errorf("nested state of the child is invalid: %+v", info.Children["child"])
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	var started int
+	for _, e := range events {
+		if e.Worker == "child/inner" && e.Kind == KindWorkerStarted {
+			started++
+		}
+	}
+	// each run of the child is reported once, regardless of the child restarts
+	if started != 2 {
+		t.Errorf("started events of the child worker with the prefixed name(%d) != 2", started)
+	}
+}
+
+type failingInitWorker struct{}
+
+func (*failingInitWorker) Init() error           { return errors.New("init failed") }
+func (*failingInitWorker) Run(ctx Context) error { <-ctx.Done(); return nil }
+
+func TestChief_RunE(t *testing.T) {
+	cases := map[StopReason]struct {
+		exitCode int
+		setup    func(chief Chief)
+	}{
+		StopBySignal: {ExitCodeOK, func(chief Chief) {
+			chief.SetLocker(func() {})
+			chief.AddWorker("worker", &blockingWorker{})
+		}},
+		StopByFatalWorker: {ExitCodeFatalWorker, func(chief Chief) {
+			chief.AddWorker("worker", &blockingWorker{})
+			chief.AddWorker("fatal", &failingWorker{}, StopAppOnFail)
+		}},
+		StopByInitFailure: {ExitCodeInitFailure, func(chief Chief) {
+			chief.AddWorker("worker", &blockingWorker{})
+			chief.AddWorker("fatal", &failingInitWorker{}, StopAppOnFail)
+		}},
+		StopNoWorkers: {ExitCodeNoWorkers, func(chief Chief) {}},
+	}
+
+	for reason, tc := range cases {
+		chief := NewChief()
+		chief.SetLocker(func() { select {} })
+		chief.SetEventHandler(func(Event) {})
+		tc.setup(chief)
+
+		errs := make(chan error, 1)
+		go func() { errs <- chief.RunE() }()
+
+		var err error
+		select {
+		case err = <-errs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: chief was not stopped in time", reason)
+		}
+
+		if code := ExitCode(err); code != tc.exitCode {
+			t.Errorf("%s: exit code(%d) != %d, error: %v", reason, code, tc.exitCode, err)
+		}
+
+		var stopErr *StopError
+		if reason != StopBySignal && (!errors.As(err, &stopErr) || stopErr.Reason != reason) {
+			t.Errorf("%s: unexpected error: %v", reason, err)
+		}
+	}
+}
+
+// readyWorker signals the readiness when the `release` channel is closed.
+type readyWorker struct {
+	release chan struct{}
+}
+
+func (w *readyWorker) Init() error { return nil }
+
+func (w *readyWorker) Run(ctx Context) error {
+	select {
+	case <-w.release:
+		SignalReady(ctx)
+	case <-ctx.Done():
+		return nil
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func TestChief_Readiness(t *testing.T) {
+	release := make(chan struct{})
+	closed := make(chan struct{})
+	close(closed)
+
+	c := NewChief().(*chief)
+	c.SetEventHandler(func(Event) {})
+	c.AddWorker("api", &readyWorker{release: release})
+	c.AddWorker("consumer", &readyWorker{release: closed}, DependsOnReady("api"))
+	c.SetLocker(func() {
+		states := func() map[WorkerName]sam.State { return c.GetWorkersStates() }
+		// the running worker is not ready until it signals the readiness
+		waitFor(func() bool { return states()["api"] == WStateRun })()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		err := c.WaitReady(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error(%v) is not %v", err, context.DeadlineExceeded)
+		}
+		if state := states()["consumer"]; IsRunning(state) {
+			t.Errorf("dependent worker was started before the dependency is ready, state: %s", state)
+		}
+		if info := c.readyInfo(nil); info.Ready || info.Workers["api"] {
+			t.Errorf("unexpected ready info: %+v", info)
+		}
+
+		close(release)
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err = c.WaitReady(ctx, "api"); err != nil {
+			t.Fatal(err)
+		}
+		// the dependent worker starts and signals the readiness only after the dependency
+		if err = c.WaitReady(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if info := c.readyInfo([]WorkerName{"api", "consumer"}); !info.Ready {
+			t.Errorf("unexpected ready info: %+v", info)
+		}
+	})
+
+	runChief(t, c, 10*time.Second)
+}
+
+type checkedWorker struct {
+	blockingWorker
+	unhealthy int32
+}
+
+func (w *checkedWorker) HealthCheck(context.Context) error {
+	if atomic.LoadInt32(&w.unhealthy) == 1 {
+		return errors.New("connection lost")
+	}
+	return nil
+}
+
+func TestChief_HealthCheck(t *testing.T) {
+	worker := &checkedWorker{}
+
+	var c Chief
+	stateIs := func(state sam.State) func() bool {
+		return func() bool { return c.GetWorkersStates()["checked"] == state }
+	}
+
+	var degradedInfo, recoveredInfo WorkerInfo
+	c = NewChief()
+	c.SetEventHandler(func(Event) {})
+	c.AddWorker("checked", worker,
+		HealthCheckOption{Interval: 10 * time.Millisecond, RestartAfter: 5})
+	c.SetLocker(func() {
+		waitFor(func() bool { return atomic.LoadInt32(&worker.runs) == 1 })()
+
+		atomic.StoreInt32(&worker.unhealthy, 1)
+		waitFor(stateIs(WStateDegraded))()
+		degradedInfo = c.GetWorkersInfo()["checked"]
+
+		atomic.StoreInt32(&worker.unhealthy, 0)
+		waitFor(stateIs(WStateRun))()
+		recoveredInfo = c.GetWorkersInfo()["checked"]
+
+		atomic.StoreInt32(&worker.unhealthy, 1)
+		waitFor(func() bool { return atomic.LoadInt32(&worker.runs) == 2 })()
+	})
+
+	runChief(t, c, 5*time.Second)
+
+	if h := degradedInfo.Health; h == nil || h.Healthy || h.Reason != "connection lost" {
+		t.Errorf("unexpected health of the degraded worker: %+v", h)
+	}
+	if h := recoveredInfo.Health; h == nil || !h.Healthy || h.ConsecutiveFailures != 0 {
+		t.Errorf("unexpected health of the recovered worker: %+v", h)
+	}
+	if worker.inits != 1 {
+		t.Errorf("inits(%d) != 1", worker.inits)
+	}
+}
+
+type heartbeatWorker struct {
+	runs int32
+}
+
+func (w *heartbeatWorker) Run(ctx Context) error {
+	run := atomic.AddInt32(&w.runs, 1)
+	for i := 0; run > 1 || i < 5; i++ {
+		Heartbeat(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	// hangs without heartbeats until the watchdog interrupts the run
+	<-ctx.Done()
+	return nil
+}
+
+func TestChief_Watchdog(t *testing.T) {
+	worker := &heartbeatWorker{}
+	var missed []Event
+	var mutex sync.Mutex
+
+	chief := NewChief()
+	chief.SetEventHandler(func(e Event) {
+		if e.Message == "Worker missed the heartbeat deadline" {
+			mutex.Lock()
+			missed = append(missed, e)
+			mutex.Unlock()
+		}
+	})
+	chief.AddWorker("hung", worker, Restart, Watchdog(50*time.Millisecond))
+	chief.SetLocker(waitFor(func() bool { return atomic.LoadInt32(&worker.runs) == 2 }))
+
+	runChief(t, chief, 5*time.Second)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(missed) != 1 {
+		t.Fatalf("missed heartbeat events(%d) != 1", len(missed))
+	}
+	if stack, _ := missed[0].Fields["stack"].(string); !strings.Contains(stack, "heartbeatWorker") {
+		t.Errorf("event does not contain the worker stack: %q", stack)
+	}
+}
+
+type panickingWorker struct {
+	runs int32
+}
+
+func (w *panickingWorker) Run(ctx Context) error {
+	switch atomic.AddInt32(&w.runs, 1) {
+	case 1:
+		panic("boom")
+	case 2:
+		return errors.New("failed")
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func TestChief_GetWorkersInfo(t *testing.T) {
+	worker := &panickingWorker{}
+	var info WorkerInfo
+
+	chief := NewChief()
+	chief.SetEventHandler(func(Event) {})
+	chief.AddWorker("panicking", worker, Restart)
+	chief.SetLocker(func() {
+		waitFor(func() bool { return atomic.LoadInt32(&worker.runs) == 3 })()
+		time.Sleep(20 * time.Millisecond)
+		info = chief.GetWorkersInfo()["panicking"]
+	})
+
+	runChief(t, chief, 5*time.Second)
+
+	if info.State != WStateRun {
+		t.Errorf("state(%s) != %s", info.State, WStateRun)
+	}
+	if info.Restarts.Total != 2 || info.Panics != 1 {
+		t.Errorf("restarts(%d) != 2 or panics(%d) != 1", info.Restarts.Total, info.Panics)
+	}
+	if info.LastError != "failed" {
+		t.Errorf("last error(%s) != failed", info.LastError)
+	}
+	if !strings.Contains(info.LastPanicStack, "panickingWorker") {
+		t.Errorf("last panic stack does not contain the worker: %q", info.LastPanicStack)
+	}
+	if info.StartedAt.IsZero() || info.Uptime <= 0 || info.LastTransition.Before(info.StartedAt) {
+		t.Errorf("invalid timestamps: %+v", info)
+	}
+}
+
+type recordingObserver struct {
+	NopObserver
+
+	mutex     sync.Mutex
+	states    []sam.State
+	inits     int
+	runs      int
+	delivered int
+	dropped   int
+}
+
+func (o *recordingObserver) WorkerStateChanged(name WorkerName, _, to sam.State) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if name == "sender" {
+		o.states = append(o.states, to)
+	}
+}
+
+func (o *recordingObserver) WorkerInitialized(WorkerName, time.Duration, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.inits++
+}
+
+func (o *recordingObserver) WorkerRunFinished(WorkerName, time.Duration, error, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.runs++
+}
+
+func (o *recordingObserver) MessageDelivered(from, to WorkerName) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if from == "sender" && to == "receiver" {
+		o.delivered++
+	}
+}
+
+func (o *recordingObserver) MessageDropped(from, to WorkerName) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if from == "sender" && to == "unknown" {
+		o.dropped++
+	}
+}
+
+type senderWorker struct {
+	blockingWorker
+}
+
+func (w *senderWorker) Run(ctx Context) error {
+	ctx.Send("receiver", "hello")
+	ctx.Send("unknown", "hello")
+	return nil
+}
+
+type receiverWorker struct {
+	received int32
+}
+
+func (w *receiverWorker) Run(ctx Context) error {
+	for {
+		select {
+		case <-ctx.Messages():
+			atomic.AddInt32(&w.received, 1)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func TestChief_Observer(t *testing.T) {
+	observer := &recordingObserver{}
+	receiver := &receiverWorker{}
+
+	chief := NewChief()
+	chief.SetEventHandler(func(Event) {})
+	chief.AddObserver(observer)
+	chief.AddWorker("sender", &senderWorker{})
+	chief.AddWorker("receiver", receiver)
+	chief.SetLocker(waitFor(func() bool {
+		observer.mutex.Lock()
+		defer observer.mutex.Unlock()
+		// the message is delivered to the mailbox before the receiver reads it
+		return observer.dropped == 1 && observer.delivered == 1 && atomic.LoadInt32(&receiver.received) == 1
+	}))
+
+	runChief(t, chief, 5*time.Second)
+
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+
+	expected := []sam.State{WStateInitialized, WStateRun, WStateStopped}
+	if len(observer.states) != len(expected) {
+		t.Fatalf("states(%v) != %v", observer.states, expected)
+	}
+	for i := range expected {
+		if observer.states[i] != expected[i] {
+			t.Errorf("states(%v) != %v", observer.states, expected)
+		}
+	}
+	if observer.inits != 1 || observer.runs < 1 {
+		t.Errorf("inits(%d) != 1 or runs(%d) < 1", observer.inits, observer.runs)
+	}
+	if received := atomic.LoadInt32(&receiver.received); received != 1 {
+		t.Errorf("received(%d) != 1", received)
+	}
+}
+
+type reloadableWorker struct {
+	mutex   sync.Mutex
+	configs []interface{}
+	fail    bool
+}
+
+func (w *reloadableWorker) Run(ctx Context) error {
+	SignalReady(ctx)
+	<-ctx.Done()
+	return nil
+}
+
+func (w *reloadableWorker) Reload(_ context.Context, config interface{}) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.configs = append(w.configs, config)
+	if w.fail {
+		return errors.New("invalid config")
+	}
+	return nil
+}
+
+func TestChief_Reload(t *testing.T) {
+	reloadable := &reloadableWorker{}
+	broken := &reloadableWorker{fail: true}
+	reInit := &blockingWorker{}
+	ignored := &blockingWorker{}
+
+	loads := 0
+	loader := func() (interface{}, error) {
+		loads++
+		if loads > 1 {
+			return nil, errors.New("file not found")
+		}
+		return TopologyConfig{Workers: []WorkerConfig{
+			{Name: "reloadable", Params: WorkerParams{"level": "debug"}},
+		}}, nil
+	}
+
+	var events []Event
+	var result ReloadResult
+	var reloadErr, loadErr error
+
+	chief := NewChief()
+	chief.SetConfigLoader(loader)
+	chief.AddEventHandler(func(e Event) {
+		switch e.Kind {
+		case KindWorkerReloaded, KindWorkerReloadFailed, KindReloadCompleted, KindReloadFailed:
+			events = append(events, e)
+		}
+	})
+	chief.AddWorker("reloadable", reloadable)
+	chief.AddWorker("broken", broken)
+	chief.AddWorker("reinit", reInit, ReInitOnReload)
+	chief.AddWorker("ignored", ignored)
+	chief.SetLocker(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := chief.WaitReady(ctx, "reloadable", "broken"); err != nil {
+			t.Error(err)
+			return
+		}
+		waitFor(func() bool { return atomic.LoadInt32(&reInit.runs) > 0 })()
+
+		result, reloadErr = chief.Reload()
+		_, loadErr = chief.Reload()
+	})
+
+	runChief(t, chief, 5*time.Second)
+
+	if !errors.Is(reloadErr, ErrReloadFailed) || !errors.Is(loadErr, ErrReloadFailed) {
+		t.Errorf("unexpected errors: %v, %v", reloadErr, loadErr)
+	}
+	if len(result.Reloaded) != 1 || result.Reloaded[0] != "reloadable" ||
+		len(result.Restarted) != 1 || result.Restarted[0] != "reinit" ||
+		result.Failed["broken"] != "invalid config" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	if len(reloadable.configs) != 1 || reloadable.configs[0].(WorkerParams)["level"] != "debug" {
+		t.Errorf("worker params were not passed: %v", reloadable.configs)
+	}
+	if reInit.inits != 2 || ignored.inits != 1 {
+		t.Errorf("inits: reinit(%d) != 2, ignored(%d) != 1", reInit.inits, ignored.inits)
+	}
+
+	kinds := make([]EventKind, 0, len(events))
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+	}
+	expected := []EventKind{KindWorkerReloaded, KindWorkerReloadFailed, KindWorkerReloaded, KindReloadCompleted, KindReloadFailed}
+	if len(kinds) != len(expected) {
+		t.Fatalf("events(%v) != %v", kinds, expected)
+	}
+	// the order of the workers without dependencies is not defined
+	counts := map[EventKind]int{}
+	for i := range kinds {
+		counts[kinds[i]]++
+		counts[expected[i]]--
+	}
+	for kind, n := range counts {
+		if n != 0 {
+			t.Errorf("unexpected number of %s events: %v", kind, kinds)
+		}
+	}
+	if kinds[3] != KindReloadCompleted || !events[3].IsWarn() {
+		t.Errorf("reload summary was not emitted as warning: %+v", events[3])
+	}
+}
@@ -1,6 +1,9 @@
 package uwe
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Context is a wrapper over the standard `context.Context`.
 // The main purpose of this is to extend in the future.
@@ -12,9 +15,127 @@ type Context interface {
 type ctx struct {
 	context.Context
 	Mailbox
+
+	env *workerEnv
+}
+
+// workerEnv holds the worker bindings to the `Chief` that launched it.
+type workerEnv struct {
+	name      WorkerName
+	emit      func(Event)
+	ready     func()
+	heartbeat func()
 }
 
 // NewContext returns new context.
 func NewContext(c context.Context, m Mailbox) Context {
 	return ctx{Context: c, Mailbox: m}
 }
+
+// SendMessage sends the prepared message through the worker mailbox.
+func (c ctx) SendMessage(msg Message) {
+	SendMessage(c.Mailbox, msg)
+}
+
+// TrySend sends the prepared message through the worker mailbox without blocking.
+func (c ctx) TrySend(msg Message) error {
+	return TrySend(c.Mailbox, msg)
+}
+
+// RequestMessage sends the prepared message as a request through the worker mailbox.
+func (c ctx) RequestMessage(reqCtx context.Context, msg Message) (*Message, error) {
+	return RequestMessage(reqCtx, c.Mailbox, msg)
+}
+
+// Publish publishes the message through the worker mailbox.
+func (c ctx) Publish(topic string, kind MessageKind, data interface{}) {
+	Publish(c.Mailbox, topic, kind, data)
+}
+
+// Subscribe subscribes the worker to the topics matching the pattern.
+func (c ctx) Subscribe(topic string) {
+	Subscribe(c.Mailbox, topic)
+}
+
+// Unsubscribe removes the subscription of the worker.
+func (c ctx) Unsubscribe(topic string) {
+	Unsubscribe(c.Mailbox, topic)
+}
+
+// ReadinessNotifier is a `Context` that can report the worker readiness to the `Chief`.
+type ReadinessNotifier interface {
+	SignalReady()
+}
+
+// SignalReady notifies the `Chief` that the worker is ready, e.g. it has bound its port.
+// The worker moves from the `WStateRun` to the `WStateReady` state.
+// The call has no effect if the context is not bound to the `Chief`.
+func SignalReady(c Context) {
+	if n, ok := c.(ReadinessNotifier); ok {
+		n.SignalReady()
+	}
+}
+
+// HeartbeatNotifier is a `Context` that can report the worker liveness to the `Chief`.
+type HeartbeatNotifier interface {
+	Heartbeat()
+}
+
+// Heartbeat notifies the `Chief` that the worker is alive.
+// The worker with the `Watchdog` option must call it within the configured interval.
+// The call has no effect if the context is not bound to the `Chief`.
+func Heartbeat(c Context) {
+	if n, ok := c.(HeartbeatNotifier); ok {
+		n.Heartbeat()
+	}
+}
+
+// newWorkerContext returns new context bound to the worker environment.
+func newWorkerContext(c context.Context, m Mailbox, env *workerEnv) Context {
+	return ctx{Context: c, Mailbox: m, env: env}
+}
+
+// withParent returns a copy of the worker context with the replaced parent `context.Context`.
+func withParent(c Context, parent context.Context) Context {
+	if wc, ok := c.(ctx); ok {
+		wc.Context = parent
+		return wc
+	}
+	return NewContext(parent, c)
+}
+
+// emitEvent sends the event to the `Chief` event stream, if context is bound to the `Chief`.
+func (c ctx) emitEvent(event Event) {
+	if c.env != nil && c.env.emit != nil {
+		c.env.emit(event)
+	}
+}
+
+// SignalReady notifies the `Chief` that the worker is ready.
+func (c ctx) SignalReady() {
+	if c.env != nil && c.env.ready != nil {
+		c.env.ready()
+	}
+}
+
+// Heartbeat notifies the `Chief` that the worker is alive.
+func (c ctx) Heartbeat() {
+	if c.env != nil && c.env.heartbeat != nil {
+		c.env.heartbeat()
+	}
+}
+
+// eventEmitter is implemented by the contexts that can deliver events to the `Chief`.
+type eventEmitter interface {
+	emitEvent(event Event)
+}
+
+// detachedContext keeps the values of the parent context, but ignores its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
@@ -0,0 +1,303 @@
+package uwe
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy defines what happens with the event when the event queue
+// or the subscription channel is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock blocks the event sender until the queue has free space.
+	// A slow handler or subscriber stalls the workers that emit events.
+	// The events emitted by the handlers into the full queue are dropped,
+	// because the queue is drained by the same goroutine which calls the handlers.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop silently drops the event.
+	OverflowDrop
+	// OverflowDropAndCount drops the event and counts it. When the queue has free space again,
+	// the handlers and subscribers receive an event with the number of dropped events.
+	OverflowDropAndCount
+)
+
+// DefaultEventQueueSize is a default size of the event queue and of each subscription channel.
+const DefaultEventQueueSize = 256
+
+// eventDispatcher delivers the `Chief` events to any number of the handlers and subscribers.
+// Events are emitted into the buffered queue and processed in a separate goroutine,
+// so the handlers do not stall the workers, unless the queue is full and `OverflowBlock` is used.
+type eventDispatcher struct {
+	size   int
+	policy OverflowPolicy
+	queue  chan Event
+
+	dropped uint64
+	// runner is an id of the dispatching goroutine.
+	runner uint64
+	// seq is a sequence number of the last delivered event, it is assigned by the dispatching goroutine,
+	// so it follows the order of the delivery.
+	seq uint64
+
+	// lifeMutex guards the state of the dispatching goroutine,
+	// it is read-locked while the event is put into the queue.
+	lifeMutex sync.RWMutex
+	running   bool
+	stop      chan struct{}
+	stopped   chan struct{}
+
+	mutex       sync.RWMutex
+	handlers    []EventHandler
+	subscribers []*subscription
+}
+
+// subscription is a channel of the subscriber.
+type subscription struct {
+	ch chan Event
+	// closed is closed by the `unsubscribe` to release the blocked delivery.
+	closed chan struct{}
+	// sendMutex is held during the delivery, so the channel is closed only when nothing is sent to it.
+	sendMutex sync.Mutex
+}
+
+func newEventDispatcher(size int, policy OverflowPolicy) *eventDispatcher {
+	if size < 1 {
+		size = 1
+	}
+
+	return &eventDispatcher{
+		size:   size,
+		policy: policy,
+		queue:  make(chan Event, size),
+	}
+}
+
+// setQueue replaces the queue size and the overflow policy.
+// Already queued events are delivered before the replacement.
+func (d *eventDispatcher) setQueue(size int, policy OverflowPolicy) {
+	if size < 1 {
+		size = 1
+	}
+
+	d.lifeMutex.Lock()
+	defer d.lifeMutex.Unlock()
+
+	d.stopRun()
+	d.size = size
+	d.policy = policy
+	d.queue = make(chan Event, size)
+}
+
+func (d *eventDispatcher) addHandler(handler EventHandler) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.handlers = append(d.handlers, handler)
+}
+
+func (d *eventDispatcher) subscribe() <-chan Event {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	s := &subscription{ch: make(chan Event, d.size), closed: make(chan struct{})}
+	d.subscribers = append(d.subscribers, s)
+	return s.ch
+}
+
+// unsubscribe removes the subscription and closes its channel.
+// The delivery blocked on the full channel is interrupted.
+func (d *eventDispatcher) unsubscribe(sub <-chan Event) {
+	var found *subscription
+
+	d.mutex.Lock()
+	for i, s := range d.subscribers {
+		if s.ch == sub {
+			d.subscribers = append(d.subscribers[:i:i], d.subscribers[i+1:]...)
+			found = s
+			break
+		}
+	}
+	d.mutex.Unlock()
+
+	if found == nil {
+		return
+	}
+
+	close(found.closed)
+	found.sendMutex.Lock()
+	close(found.ch)
+	found.sendMutex.Unlock()
+}
+
+// send passes the event to the subscriber and returns false if the event is dropped because the channel is full.
+// If the `block` is set, it waits for the free space until the subscription or the dispatcher is stopped.
+func (s *subscription) send(event Event, block bool, stop <-chan struct{}) bool {
+	s.sendMutex.Lock()
+	defer s.sendMutex.Unlock()
+
+	select {
+	case <-s.closed:
+		return true
+	default:
+	}
+
+	select {
+	case s.ch <- event:
+		return true
+	default:
+	}
+
+	if !block {
+		return false
+	}
+
+	select {
+	case s.ch <- event:
+	case <-s.closed:
+	case <-stop:
+	}
+	return true
+}
+
+// emit puts the event into the queue according to the `OverflowPolicy`.
+// The event gets the emission time, if it is not set, and the next sequence number on the delivery.
+func (d *eventDispatcher) emit(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Kind == "" {
+		event.Kind = KindGeneric
+	}
+
+	// the handler can not wait for the queue which is drained by its own goroutine,
+	// the queue is not replaced until the handler returns
+	if goroutineID() == atomic.LoadUint64(&d.runner) {
+		select {
+		case d.queue <- event:
+		default:
+			d.drop(d.policy)
+		}
+		return
+	}
+
+	d.lifeMutex.RLock()
+	if !d.running {
+		d.lifeMutex.RUnlock()
+		d.start()
+		d.lifeMutex.RLock()
+	}
+	defer d.lifeMutex.RUnlock()
+
+	if d.policy == OverflowBlock {
+		d.queue <- event
+		return
+	}
+
+	select {
+	case d.queue <- event:
+	default:
+		d.drop(d.policy)
+	}
+}
+
+// start launches the dispatching goroutine, if it is not running.
+func (d *eventDispatcher) start() {
+	d.lifeMutex.Lock()
+	defer d.lifeMutex.Unlock()
+
+	if d.running {
+		return
+	}
+
+	d.running = true
+	d.stop = make(chan struct{})
+	d.stopped = make(chan struct{})
+	go d.run(d.queue, d.policy, d.stop, d.stopped)
+}
+
+// close delivers all queued events and stops the dispatching goroutine.
+// The goroutine is started again by the next emitted event.
+func (d *eventDispatcher) close() {
+	d.lifeMutex.Lock()
+	defer d.lifeMutex.Unlock()
+
+	d.stopRun()
+}
+
+// stopRun stops the dispatching goroutine, if it is running.
+// It must be called with the locked `lifeMutex`.
+func (d *eventDispatcher) stopRun() {
+	if !d.running {
+		return
+	}
+
+	close(d.stop)
+	<-d.stopped
+	d.running = false
+}
+
+func (d *eventDispatcher) run(queue <-chan Event, policy OverflowPolicy, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+	atomic.StoreUint64(&d.runner, goroutineID())
+	defer atomic.StoreUint64(&d.runner, 0)
+
+	for {
+		select {
+		case event := <-queue:
+			d.deliver(event, policy, stop)
+		case <-stop:
+			for {
+				select {
+				case event := <-queue:
+					d.deliver(event, policy, stop)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver passes the event to all handlers and subscribers.
+// The lists are copied, so the handlers and subscribers are called without the lock.
+// The blocked delivery to the subscriber is interrupted when the dispatcher is stopped.
+func (d *eventDispatcher) deliver(event Event, policy OverflowPolicy, stop <-chan struct{}) {
+	if policy == OverflowDropAndCount {
+		if dropped := atomic.SwapUint64(&d.dropped, 0); dropped > 0 {
+			d.deliver(Event{
+				Time:    time.Now(),
+				Level:   LvlWarn,
+				Kind:    KindEventsDropped,
+				Message: "Events were dropped due to the overflow",
+				Fields:  map[string]interface{}{"dropped": dropped},
+			}, policy, stop)
+		}
+	}
+
+	d.seq++
+	event.Seq = d.seq
+
+	d.mutex.RLock()
+	handlers := append([]EventHandler(nil), d.handlers...)
+	subscribers := append([]*subscription(nil), d.subscribers...)
+	d.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+
+	for _, s := range subscribers {
+		if !s.send(event, policy == OverflowBlock, stop) {
+			d.drop(policy)
+		}
+	}
+}
+
+// drop accounts the dropped event, if required by the `OverflowPolicy`.
+func (d *eventDispatcher) drop(policy OverflowPolicy) {
+	if policy == OverflowDropAndCount {
+		atomic.AddUint64(&d.dropped, 1)
+	}
+}
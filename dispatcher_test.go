@@ -0,0 +1,134 @@
+package uwe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventDispatcher_Subscribers(t *testing.T) {
+	d := newEventDispatcher(4, OverflowBlock)
+
+	var handled []string
+	d.addHandler(func(e Event) { handled = append(handled, e.Message) })
+	d.addHandler(func(e Event) { handled = append(handled, e.Message) })
+	first, second := d.subscribe(), d.subscribe()
+
+	d.emit(Event{Message: "a"})
+	d.emit(Event{Message: "b"})
+	d.close()
+
+	if len(handled) != 4 {
+		t.Errorf("handled events(%d) != 4", len(handled))
+	}
+	for _, sub := range []<-chan Event{first, second} {
+		if len(sub) != 2 || (<-sub).Message != "a" || (<-sub).Message != "b" {
+			t.Error("subscriber did not receive all events in order")
+		}
+	}
+
+	d.unsubscribe(first)
+	if _, ok := <-first; ok {
+		t.Error("subscription channel was not closed")
+	}
+}
+
+func TestEventDispatcher_Overflow(t *testing.T) {
+	release := make(chan struct{})
+	d := newEventDispatcher(1, OverflowDropAndCount)
+
+	var handled []Event
+	d.addHandler(func(e Event) {
+		<-release
+		handled = append(handled, e)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			d.emit(Event{Message: "event"})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("emit was blocked by the slow handler")
+	}
+
+	close(release)
+	d.emit(Event{Message: "last"})
+	d.close()
+
+	var dropped uint64
+	for _, e := range handled {
+		if n, ok := e.Fields["dropped"].(uint64); ok {
+			dropped += n
+		}
+	}
+	if dropped == 0 || int(dropped)+len(handled)-1 != 11 {
+		t.Errorf("dropped(%d) and handled(%d) events do not match the emitted ones", dropped, len(handled))
+	}
+}
+
+func TestEventDispatcher_UnsubscribeBlocked(t *testing.T) {
+	d := newEventDispatcher(1, OverflowBlock)
+	sub := d.subscribe()
+
+	d.emit(Event{Message: "a"})
+	d.emit(Event{Message: "b"})
+	// "b" is taken from the queue, the delivery waits for the full subscription channel
+	for len(d.queue) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	unsubscribed := make(chan struct{})
+	go func() {
+		d.unsubscribe(sub)
+		close(unsubscribed)
+	}()
+
+	select {
+	case <-unsubscribed:
+	case <-time.After(time.Second):
+		t.Fatal("unsubscribe was blocked by the delivery")
+	}
+
+	if e := <-sub; e.Message != "a" {
+		t.Errorf("event(%s) != a", e.Message)
+	}
+	for range sub {
+	}
+	d.close()
+}
+
+func TestEventDispatcher_HandlerEmit(t *testing.T) {
+	d := newEventDispatcher(1, OverflowBlock)
+
+	var handled []string
+	d.addHandler(func(e Event) {
+		handled = append(handled, e.Message)
+		if e.Message == "outer" {
+			for i := 0; i < 3; i++ {
+				d.emit(Event{Message: "inner"})
+			}
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		d.emit(Event{Message: "outer"})
+		d.close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler was blocked by the full queue")
+	}
+
+	if len(handled) < 2 || len(handled) > 4 || handled[0] != "outer" {
+		t.Errorf("unexpected handled events: %v", handled)
+	}
+}
@@ -3,6 +3,7 @@ package uwe
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // EventLevel ...
@@ -11,16 +12,65 @@ type EventLevel string
 const (
 	LvlFatal EventLevel = "fatal"
 	LvlError EventLevel = "error"
+	LvlWarn  EventLevel = "warn"
 	LvlInfo  EventLevel = "info"
+	LvlDebug EventLevel = "debug"
+)
+
+// EventKind is a type of the event, that allows handlers
+// to react to the particular events without matching the messages.
+type EventKind string
+
+const (
+	// KindGeneric is a kind of the event that does not belong to any other kind.
+	KindGeneric EventKind = "generic"
+	// KindError is a kind of the internal error of the `Chief`.
+	KindError EventKind = "error"
+
+	KindWorkerWaitingDependencies EventKind = "worker_waiting_dependencies"
+	KindWorkerInitialized         EventKind = "worker_initialized"
+	KindWorkerInitFailed          EventKind = "worker_init_failed"
+	KindWorkerStarting            EventKind = "worker_starting"
+	KindWorkerStarted             EventKind = "worker_started"
+	KindWorkerReady               EventKind = "worker_ready"
+	KindWorkerStopped             EventKind = "worker_stopped"
+	KindWorkerFailed              EventKind = "worker_failed"
+	KindWorkerPanicked            EventKind = "worker_panicked"
+	KindWorkerRestarting          EventKind = "worker_restarting"
+	KindWorkerRestartLimit        EventKind = "worker_restart_limit"
+	KindWorkerDegraded            EventKind = "worker_degraded"
+	KindWorkerRecovered           EventKind = "worker_recovered"
+	KindWorkerHeartbeatMissed     EventKind = "worker_heartbeat_missed"
+	KindWorkerStuck               EventKind = "worker_stuck"
+	KindWorkerControl             EventKind = "worker_control"
+	KindGroupRestarting           EventKind = "group_restarting"
+	KindChiefStopping             EventKind = "chief_stopping"
+	KindWorkerReloaded            EventKind = "worker_reloaded"
+	KindWorkerReloadFailed        EventKind = "worker_reload_failed"
+	KindReloadCompleted           EventKind = "reload_completed"
+	KindReloadFailed              EventKind = "reload_failed"
+	KindSignalReceived            EventKind = "signal_received"
+	KindForceExit                 EventKind = "force_exit"
+	KindStateDump                 EventKind = "state_dump"
+	KindEventsDropped             EventKind = "events_dropped"
+	KindMessageDeadLettered       EventKind = "message_dead_lettered"
 )
 
 // Event is a message object that is used to signalize
 // about Chief's internal events and processed by `EventHandlers`.
 type Event struct {
 	Level   EventLevel
+	Kind    EventKind
 	Worker  WorkerName
 	Fields  map[string]interface{}
 	Message string
+	// Err is the original error, if the event is caused by the error.
+	// The `Fields["error"]` keeps its message for the log handlers.
+	Err error
+	// Time is a moment when the event was emitted.
+	Time time.Time
+	// Seq is a sequence number of the event, it increases monotonically within the `Chief`.
+	Seq uint64
 }
 
 // IsFatal returns `true` if event level is `Fatal`
@@ -33,11 +83,24 @@ func (e Event) IsError() bool {
 	return e.Level == LvlError
 }
 
+// IsWarn returns `true` if event level is `Warn`
+func (e Event) IsWarn() bool {
+	return e.Level == LvlWarn
+}
+
 // ToError validates event level and cast to builtin `error`.
+// If the event holds the original error, it is wrapped,
+// so it can be checked with the `errors.Is` and `errors.As`.
 func (e Event) ToError() error {
 	if !e.IsError() && !e.IsFatal() {
 		return nil
 	}
+	if e.Err != nil {
+		if e.Message == e.Err.Error() {
+			return e.Err
+		}
+		return fmt.Errorf("%s: %w", e.Message, e.Err)
+	}
 	return errors.New(e.Message)
 }
 
@@ -53,6 +116,9 @@ func (e Event) FormatFields() string {
 
 // SetField add to event some Key/Value.
 func (e Event) SetField(key string, value interface{}) Event {
+	if e.Fields == nil {
+		e.Fields = map[string]interface{}{}
+	}
 	e.Fields[key] = value
 	return e
 }
@@ -67,6 +133,18 @@ func (e Event) SetWorker(name WorkerName) Event {
 func ErrorEvent(msg string) Event {
 	return Event{
 		Level:   LvlError,
+		Kind:    KindError,
 		Message: msg,
 	}
 }
+
+// ErrorEventFrom returns new Event with `LvlError` that holds the provided error.
+func ErrorEventFrom(err error) Event {
+	return Event{
+		Level:   LvlError,
+		Kind:    KindError,
+		Message: err.Error(),
+		Fields:  map[string]interface{}{"error": err.Error()},
+		Err:     err,
+	}
+}
@@ -0,0 +1,56 @@
+package uwe
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestEvent_ToError(t *testing.T) {
+	if err := (Event{Level: LvlWarn, Err: io.EOF}).ToError(); err != nil {
+		t.Errorf("warn event was converted to error: %s", err)
+	}
+
+	for _, event := range []Event{
+		ErrorEventFrom(io.EOF),
+		{Level: LvlFatal, Message: "worker failed", Err: io.EOF},
+	} {
+		err := event.ToError()
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("original error was not preserved: %v", err)
+		}
+		if err.Error() != event.Message && err.Error() != event.Message+": "+io.EOF.Error() {
+			t.Errorf("unexpected error message: %s", err)
+		}
+	}
+
+	if err := ErrorEvent("failed").ToError(); err == nil || err.Error() != "failed" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEventDispatcher_Stamps(t *testing.T) {
+	d := newEventDispatcher(8, OverflowBlock)
+	sub := d.subscribe()
+
+	emitted := time.Now().Add(-time.Hour)
+	d.emit(Event{Message: "a"})
+	d.emit(Event{Message: "b", Kind: KindWorkerStarted, Time: emitted, Seq: 100})
+	d.emit(Event{}.SetField("key", "value"))
+	d.close()
+
+	a, b, c := <-sub, <-sub, <-sub
+	if a.Seq != 1 || b.Seq != 2 || c.Seq != 3 {
+		t.Errorf("unexpected sequence: %d, %d, %d", a.Seq, b.Seq, c.Seq)
+	}
+	if a.Kind != KindGeneric || b.Kind != KindWorkerStarted {
+		t.Errorf("unexpected kinds: %s, %s", a.Kind, b.Kind)
+	}
+	if a.Time.IsZero() || !b.Time.Equal(emitted) {
+		t.Errorf("unexpected times: %s, %s", a.Time, b.Time)
+	}
+	if c.Fields["key"] != "value" {
+		t.Errorf("field was not set: %v", c.Fields)
+	}
+}
@@ -23,7 +23,7 @@ func (d panicDummy) Run(ctx uwe.Context) error {
 func main() {
 	chief := uwe.NewChief()
 
-	chief.SetEventHandler(func(event uwe.Event) {
+	chief.AddEventHandler(func(event uwe.Event) {
 		if event.IsFatal() || event.IsError() {
 			fmt.Println(event.ToError())
 			return
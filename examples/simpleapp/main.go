@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -24,7 +25,7 @@ func main() {
 	server := api.NewServer(cfg, router())
 
 	chief := uwe.NewChief()
-	chief.SetEventHandler(uwe.STDLogEventHandler())
+	chief.AddEventHandler(uwe.STDLogEventHandler())
 	//chief.UseNopIMQBroker()
 	chief.AddWorker(workerAPI, server, uwe.RestartOnFail)
 
@@ -37,12 +38,12 @@ func main() {
 
 	chief.AddWorker("no-restart", &daemon{name: "no-restart"}, uwe.NoRestart)
 
-	chief.AddWorker("restart", &daemon{name: "restart"}, uwe.Restart)
+	chief.AddWorker("restart", &daemon{name: "restart"}, uwe.Restart, uwe.DefaultBackoff())
 	chief.AddWorker("restart-full", &daemon{name: "restart-full"}, uwe.RestartAndReInit)
 
 	chief.AddWorker("show-stopper", &daemon{name: "show-stopper"}, uwe.StopAppOnFail)
 
-	chief.Run()
+	os.Exit(uwe.ExitCode(chief.RunE()))
 }
 
 func router() http.Handler {
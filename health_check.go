@@ -2,6 +2,7 @@ package uwe
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/sheb-gregor/sam"
 )
@@ -11,6 +12,17 @@ const (
 	StatusAction = "status"
 	// PingAction is a simple command that returns the "pong" message.
 	PingAction = "ping"
+	// ReadyAction is a command useful for readiness probes,
+	// it returns `socket.StatusOk` only if all requested workers are ready.
+	// The list of workers can be passed as a JSON array in the request args,
+	// otherwise all workers are checked.
+	ReadyAction = "ready"
+	// ReloadAction is a command that triggers the reload of the `Chief`, same as the SIGHUP signal.
+	// It returns the `ReloadResult`.
+	ReloadAction = "reload"
+	// DeadLettersAction is a command that returns the last messages which were not delivered,
+	// see the `DeadLetter`.
+	DeadLettersAction = "dead_letters"
 )
 
 // AppInfo is a details of the *Application* build.
@@ -28,8 +40,60 @@ func (app AppInfo) SocketName() string {
 
 // StateInfo is result the `StatusAction` command.
 type StateInfo struct {
-	App     AppInfo                  `json:"app"`
-	Workers map[WorkerName]sam.State `json:"workers"`
+	App     AppInfo                   `json:"app"`
+	Workers map[WorkerName]sam.State  `json:"workers"`
+	Details map[WorkerName]WorkerInfo `json:"details,omitempty"`
+	// Children is a states of the child supervisors,
+	// that are launched as workers with the `NewSupervisorWorker`.
+	Children map[WorkerName]*StateInfo `json:"children,omitempty"`
+}
+
+// WorkerInfo is a runtime details of the worker.
+type WorkerInfo struct {
+	State sam.State `json:"state"`
+	// StartedAt is a time when the current or the last run of the worker was started.
+	StartedAt time.Time `json:"started_at"`
+	// Uptime is a duration of the current run, it is set only while the worker is running.
+	Uptime time.Duration `json:"uptime,omitempty"`
+	// LastTransition is a time of the last state change.
+	LastTransition time.Time    `json:"last_transition"`
+	Restarts       RestartStats `json:"restarts"`
+	// Panics is a number of the worker panics since the `Chief` start.
+	Panics int `json:"panics"`
+	// LastError is a message of the last error returned by the `Init` or `Run`, or of the last panic.
+	LastError string `json:"last_error,omitempty"`
+	// LastPanicStack is a stack trace of the last panic.
+	LastPanicStack string `json:"last_panic_stack,omitempty"`
+	// Health is set only for the workers which implement `WorkerWithHealthCheck`.
+	Health *HealthInfo `json:"health,omitempty"`
+}
+
+// HealthInfo is a result of the worker health checks.
+type HealthInfo struct {
+	Healthy bool `json:"healthy"`
+	// Reason is an error of the last failed check.
+	Reason string `json:"reason,omitempty"`
+	// ConsecutiveFailures is a number of failed checks in a row.
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	LastCheck           time.Time `json:"last_check"`
+}
+
+// RestartStats is a restart counters of the worker.
+type RestartStats struct {
+	// Total is a number of restarts since the `Chief` start.
+	Total int `json:"total"`
+	// InWindow is a number of restarts within the `RestartLimit.Window`.
+	InWindow int `json:"in_window"`
+	// MaxRestarts is a `RestartLimit.MaxRestarts`, if limit is set.
+	MaxRestarts int `json:"max_restarts,omitempty"`
+	// Window is a `RestartLimit.Window`, if limit is set.
+	Window time.Duration `json:"window,omitempty"`
+}
+
+// ReadyInfo is result the `ReadyAction` command.
+type ReadyInfo struct {
+	Ready   bool                `json:"ready"`
+	Workers map[WorkerName]bool `json:"workers"`
 }
 
 // ParseStateInfo decodes `StateInfo` from the JSON response for the `StatusAction` command.
@@ -0,0 +1,37 @@
+package uwe
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sheb-gregor/sam"
+)
+
+func TestParseStateInfo(t *testing.T) {
+	// the response of the previous versions without the details
+	legacy := json.RawMessage(`{"app":{"name":"app"},"workers":{"api":"Run"}}`)
+
+	info, err := ParseStateInfo(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Workers["api"] != WStateRun || info.Details != nil {
+		t.Errorf("unexpected state info: %+v", info)
+	}
+
+	data, err := json.Marshal(StateInfo{
+		Workers: map[WorkerName]sam.State{"api": WStateFailed},
+		Details: map[WorkerName]WorkerInfo{"api": {State: WStateFailed, Panics: 1, LastError: "boom"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = ParseStateInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := info.Details["api"]; d.Panics != 1 || d.LastError != "boom" {
+		t.Errorf("unexpected worker details: %+v", d)
+	}
+}
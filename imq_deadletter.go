@@ -0,0 +1,175 @@
+package uwe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultDeadLetterCapacity is a default number of the last dead letters kept for the inspection.
+const DefaultDeadLetterCapacity = 100
+
+// DeadLetterReason is a reason why the message was not delivered.
+type DeadLetterReason string
+
+const (
+	// DeadLetterUnknownTarget means that the target worker is not registered in the broker.
+	DeadLetterUnknownTarget DeadLetterReason = "unknown_target"
+	// DeadLetterTargetStopped means that the target worker stopped before the message was put into its mailbox.
+	DeadLetterTargetStopped DeadLetterReason = "target_stopped"
+	// DeadLetterMailboxFull means that the message was rejected or evicted by the overflow policy of the mailbox.
+	DeadLetterMailboxFull DeadLetterReason = "mailbox_full"
+	// DeadLetterCanceled means that the sender gave up waiting for the free space in the mailbox,
+	// for example, the deadline of the request expired.
+	DeadLetterCanceled DeadLetterReason = "canceled"
+)
+
+// deadLetterReason returns the reason by the delivery error.
+func deadLetterReason(err error) DeadLetterReason {
+	switch {
+	case errors.Is(err, ErrUnknownTarget):
+		return DeadLetterUnknownTarget
+	case errors.Is(err, ErrTargetStopped):
+		return DeadLetterTargetStopped
+	case errors.Is(err, ErrMailboxFull):
+		return DeadLetterMailboxFull
+	default:
+		return DeadLetterCanceled
+	}
+}
+
+// DeadLetter is a message which was not delivered to the worker.
+type DeadLetter struct {
+	Time   time.Time
+	Reason DeadLetterReason
+	// Receiver is a worker to which the message was not delivered.
+	// It differs from the `Message.Target` for the broadcast messages.
+	Receiver WorkerName
+	Message  Message
+}
+
+// MarshalJSON encodes the dead letter for the `DeadLettersAction`.
+// The data of the message is encoded as is, if it is possible, otherwise it is formatted as a string.
+func (d DeadLetter) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(d.Message.Data)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%+v", d.Message.Data))
+	}
+
+	return json.Marshal(struct {
+		Time          time.Time         `json:"time"`
+		Reason        DeadLetterReason  `json:"reason"`
+		Receiver      WorkerName        `json:"receiver,omitempty"`
+		Sender        WorkerName        `json:"sender,omitempty"`
+		Target        WorkerName        `json:"target,omitempty"`
+		Kind          MessageKind       `json:"kind"`
+		Topic         string            `json:"topic,omitempty"`
+		CorrelationID string            `json:"correlation_id,omitempty"`
+		Meta          map[string]string `json:"meta,omitempty"`
+		Data          json.RawMessage   `json:"data"`
+	}{
+		Time:          d.Time,
+		Reason:        d.Reason,
+		Receiver:      d.Receiver,
+		Sender:        d.Message.Sender,
+		Target:        d.Message.Target,
+		Kind:          d.Message.Kind,
+		Topic:         d.Message.Topic,
+		CorrelationID: d.Message.CorrelationID,
+		Meta:          d.Message.Meta,
+		Data:          data,
+	})
+}
+
+// DeadLetterConfig configures the handling of the messages which can not be delivered.
+type DeadLetterConfig struct {
+	// Handler is called synchronously by the sender of the message, so it must not block.
+	Handler func(DeadLetter)
+	// Worker receives the dead letters into its mailbox as the `Message.Data`.
+	// The dead letters are not redirected to the worker if its mailbox is full.
+	Worker WorkerName
+	// Capacity is a number of the last dead letters kept for the inspection,
+	// by default the `DefaultDeadLetterCapacity` is used.
+	Capacity int
+}
+
+// DeadLetterBroker is an `IMQBroker` that keeps the messages which can not be delivered.
+// The `Chief` passes its `DeadLetterConfig` to the broker that implements this interface
+// and emits the `KindMessageDeadLettered` event for each dead letter.
+type DeadLetterBroker interface {
+	IMQBroker
+	SetDeadLetterConfig(DeadLetterConfig)
+	// DeadLetters returns the last dead letters, the oldest first.
+	DeadLetters() []DeadLetter
+}
+
+// deadLetterQueue is a ring buffer of the last dead letters.
+type deadLetterQueue struct {
+	mutex   sync.Mutex
+	config  DeadLetterConfig
+	letters []DeadLetter
+	next    int
+	count   int
+}
+
+func newDeadLetterQueue() *deadLetterQueue {
+	return &deadLetterQueue{
+		config:  DeadLetterConfig{Capacity: DefaultDeadLetterCapacity},
+		letters: make([]DeadLetter, DefaultDeadLetterCapacity),
+	}
+}
+
+// setConfig applies the config, the last dead letters which fit into the new capacity are kept.
+func (q *deadLetterQueue) setConfig(config DeadLetterConfig) {
+	if config.Capacity < 1 {
+		config.Capacity = DefaultDeadLetterCapacity
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if config.Capacity != len(q.letters) {
+		list := q.list()
+		if len(list) > config.Capacity {
+			list = list[len(list)-config.Capacity:]
+		}
+
+		q.letters = make([]DeadLetter, config.Capacity)
+		q.count = copy(q.letters, list)
+		q.next = q.count % config.Capacity
+	}
+	q.config = config
+}
+
+// add puts the dead letter into the queue and returns the actual config.
+func (q *deadLetterQueue) add(letter DeadLetter) DeadLetterConfig {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.letters[q.next] = letter
+	q.next = (q.next + 1) % len(q.letters)
+	if q.count < len(q.letters) {
+		q.count++
+	}
+	return q.config
+}
+
+// all returns the dead letters, the oldest first.
+func (q *deadLetterQueue) all() []DeadLetter {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.list()
+}
+
+// list returns the dead letters, the oldest first. It must be called with the locked mutex.
+func (q *deadLetterQueue) list() []DeadLetter {
+	list := make([]DeadLetter, 0, q.count)
+	start := (q.next - q.count + len(q.letters)) % len(q.letters)
+	for i := 0; i < q.count; i++ {
+		list = append(list, q.letters[(start+i)%len(q.letters)])
+	}
+	return list
+}
@@ -0,0 +1,154 @@
+package uwe
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBroker_DeadLetters(t *testing.T) {
+	var handled []DeadLetter
+
+	broker := NewBroker(10)
+	broker.SetDeadLetterConfig(DeadLetterConfig{
+		Handler:  func(letter DeadLetter) { handled = append(handled, letter) },
+		Worker:   "dlq",
+		Capacity: 2,
+	})
+	broker.SetMailboxOption("full", MailboxOption{Capacity: 1, Overflow: MailboxFail})
+	dlq := broker.AddWorker("dlq")
+	broker.AddWorker("full")
+	bus := broker.DefaultBus()
+
+	bus.Send("ghost", 1)
+	bus.Send("full", 2)
+	bus.Send("full", 3)
+	broker.WorkerStopped("full")
+	if err := TrySend(bus, Message{Target: "full", Data: 4, CorrelationID: "1"}); !errors.Is(err, ErrTargetStopped) {
+		t.Errorf("error(%v) is not %v", err, ErrTargetStopped)
+	}
+
+	expected := []DeadLetterReason{DeadLetterUnknownTarget, DeadLetterMailboxFull, DeadLetterTargetStopped}
+	data := []interface{}{1, 3, 4}
+	if len(handled) != len(expected) {
+		t.Fatalf("handled dead letters(%d) != %d", len(handled), len(expected))
+	}
+	for i, reason := range expected {
+		if handled[i].Reason != reason || handled[i].Message.Data != data[i] {
+			t.Errorf("dead letter %d: %+v, expected reason %s", i, handled[i], reason)
+		}
+	}
+	if handled[0].Receiver != "ghost" || handled[1].Receiver != "full" {
+		t.Errorf("unexpected receivers: %s, %s", handled[0].Receiver, handled[1].Receiver)
+	}
+
+	letters := broker.DeadLetters()
+	if len(letters) != 2 || letters[0].Reason != DeadLetterMailboxFull || letters[1].Reason != DeadLetterTargetStopped {
+		t.Errorf("only the last 2 dead letters must be kept: %+v", letters)
+	}
+
+	for i := range expected {
+		msg := <-dlq.Messages()
+		if letter, ok := msg.Data.(DeadLetter); !ok || letter.Reason != expected[i] {
+			t.Errorf("unexpected message in the dead letter worker mailbox: %+v", msg)
+		}
+	}
+
+	encoded, err := json.Marshal(DeadLetter{Reason: DeadLetterUnknownTarget, Message: Message{Data: func() {}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"reason":"unknown_target"`) || !strings.Contains(string(encoded), `"data":"0x`) {
+		t.Errorf("unexpected JSON: %s", encoded)
+	}
+}
+
+func TestBroker_DeadLetterStoppedWorker(t *testing.T) {
+	var handled []DeadLetter
+
+	broker := NewBroker(10)
+	broker.SetDeadLetterConfig(DeadLetterConfig{Handler: func(letter DeadLetter) { handled = append(handled, letter) }})
+	broker.AddWorker("worker")
+	bus := broker.DefaultBus()
+
+	broker.WorkerStopped("worker")
+	bus.Send("worker", "lost")
+	if len(handled) != 1 || handled[0].Reason != DeadLetterTargetStopped || handled[0].Message.Data != "lost" {
+		t.Errorf("message to the stopped worker was not dead-lettered: %+v", handled)
+	}
+
+	mailbox := broker.AddWorker("worker")
+	bus.Send("worker", "delivered")
+	if got := receiveAll(mailbox); len(got) != 1 || got[0] != "delivered" {
+		t.Errorf("received(%v) != [delivered]", got)
+	}
+}
+
+func TestBroker_DeadLetterEvicted(t *testing.T) {
+	var handled []DeadLetter
+
+	broker := NewBroker(10)
+	broker.SetMailboxOption("worker", MailboxOption{Capacity: 1, Overflow: MailboxDropOldest})
+	mailbox := broker.AddWorker("worker")
+	bus := broker.DefaultBus()
+
+	// the handler sends to the mailbox which evicted the message
+	broker.SetDeadLetterConfig(DeadLetterConfig{Handler: func(letter DeadLetter) {
+		handled = append(handled, letter)
+		if len(handled) == 1 {
+			bus.Send("worker", "retried")
+		}
+	}})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bus.Send("worker", 1)
+		bus.Send("worker", 2)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dead letter handler was blocked by the mailbox")
+	}
+
+	if len(handled) != 2 || handled[0].Message.Data != 1 || handled[1].Message.Data != 2 {
+		t.Errorf("unexpected dead letters: %+v", handled)
+	}
+	if got := receiveAll(mailbox); len(got) != 1 || got[0] != "retried" {
+		t.Errorf("received(%v) != [retried]", got)
+	}
+}
+
+type lostMessageWorker struct{}
+
+func (lostMessageWorker) Init() error { return nil }
+
+func (lostMessageWorker) Run(ctx Context) error {
+	ctx.SendWithKind("ghost", 5, "lost")
+	<-ctx.Done()
+	return nil
+}
+
+func TestChief_DeadLetters(t *testing.T) {
+	events := make(chan Event, 100)
+	chief := NewChief()
+	chief.AddEventHandler(func(e Event) { events <- e })
+	chief.AddWorker("sender", lostMessageWorker{})
+	chief.SetLocker(func() {
+		e := waitEvent(t, events, KindMessageDeadLettered)
+		if e.Worker != "ghost" || e.Fields["reason"] != string(DeadLetterUnknownTarget) || e.Fields["sender"] != "sender" {
+			t.Errorf("unexpected event: %+v", e)
+		}
+
+		letters := chief.GetDeadLetters()
+		if len(letters) != 1 || letters[0].Message.Data != "lost" || letters[0].Message.Kind != 5 {
+			t.Errorf("unexpected dead letters: %+v", letters)
+		}
+	})
+
+	runChief(t, chief, 10*time.Second)
+}
@@ -2,6 +2,10 @@ package uwe
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type IMQBroker interface {
@@ -11,81 +15,340 @@ type IMQBroker interface {
 	Serve(ctx context.Context)
 }
 
+// RemovableBroker is an `IMQBroker` that can release the mailbox of the removed worker.
+// The `Chief` calls the `RemoveWorker` when the worker is removed with the `(Chief).RemoveWorker`.
+type RemovableBroker interface {
+	IMQBroker
+	RemoveWorker(name WorkerName)
+}
+
+// Broker is the default `IMQBroker`. Each worker has a bounded mailbox,
+// the messages are put into it directly by the senders, so the order of the messages
+// from one sender to one receiver is preserved and the `MailboxOption` of the receiver
+// defines what happens when the receiver is slower than the senders.
 type Broker struct {
-	defaultChanLen  int
-	workersHub      map[WorkerName]chan<- *Message
-	workersMessages chan *Message
+	mutex          sync.RWMutex
+	defaultMailbox MailboxOption
+	mailboxes      map[WorkerName]*mailbox
+	options        map[WorkerName]MailboxOption
+
+	observer    Observer
+	requests    *Correlator
+	topics      *Subscriptions
+	deadLetters *deadLetterQueue
 }
 
+// NewBroker returns the `Broker` in which the mailboxes
+// of the workers without the `MailboxOption` hold the `defaultChanLen` messages
+// and block the senders when they are full, so the messages are not lost.
+// The dropping of the messages is enabled per worker by the `MailboxOption`, see the `MailboxOverflow`.
 func NewBroker(defaultChanLen int) *Broker {
 	if defaultChanLen < 1 {
 		defaultChanLen = 1
 	}
 
 	return &Broker{
-		workersHub:      map[WorkerName]chan<- *Message{},
-		workersMessages: make(chan *Message, defaultChanLen)}
+		defaultMailbox: MailboxOption{Capacity: defaultChanLen, Overflow: MailboxBlock},
+		mailboxes:      map[WorkerName]*mailbox{},
+		options:        map[WorkerName]MailboxOption{},
+		observer:       NopObserver{},
+		requests:       NewCorrelator(),
+		topics:         NewSubscriptions(),
+		deadLetters:    newDeadLetterQueue(),
+	}
+}
+
+// SetObserver sets the `Observer` that receives notifications about the messages.
+// It must be called before the `Serve`.
+func (hub *Broker) SetObserver(observer Observer) {
+	if observer == nil {
+		observer = NopObserver{}
+	}
+	hub.observer = observer
+}
+
+// SetMailboxOption sets the capacity and the overflow policy of the worker mailbox.
+// It is applied when the mailbox is created by the first `AddWorker` call.
+func (hub *Broker) SetMailboxOption(name WorkerName, opt MailboxOption) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	hub.options[name] = opt
+}
+
+// SetDeadLetterConfig sets the handling of the messages which can not be delivered.
+func (hub *Broker) SetDeadLetterConfig(config DeadLetterConfig) {
+	hub.deadLetters.setConfig(config)
+}
+
+// DeadLetters returns the last messages which were not delivered, the oldest first.
+func (hub *Broker) DeadLetters() []DeadLetter {
+	return hub.deadLetters.all()
+}
+
+// MailboxDepths returns the number of messages waiting in the mailbox of each worker.
+func (hub *Broker) MailboxDepths() map[WorkerName]int {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	r := make(map[WorkerName]int, len(hub.mailboxes))
+	for name, m := range hub.mailboxes {
+		r[name] = len(m.ch)
+	}
+	return r
+}
+
+// DroppedMessages returns the number of messages which were not put into the mailbox of each worker
+// because of the overflow or because the worker stopped.
+func (hub *Broker) DroppedMessages() map[WorkerName]uint64 {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+
+	r := make(map[WorkerName]uint64, len(hub.mailboxes))
+	for name, m := range hub.mailboxes {
+		r[name] = atomic.LoadUint64(&m.dropped)
+	}
+	return r
 }
 
 func (hub *Broker) DefaultBus() SenderBus {
-	return NewSenderBus(hub.workersMessages)
+	return &eventBus{
+		writeOnly: true,
+		in:        hub.defaultMailbox.newChan(),
+		requests:  hub.requests,
+		deliver:   hub.deliver,
+	}
 }
 
+// AddWorker returns the bus of the worker. The mailbox is kept between the worker launches,
+// so the messages which were sent while the worker restarted are not lost.
 func (hub *Broker) AddWorker(name WorkerName) Mailbox {
-	workerDirectChan := make(chan *Message, hub.defaultChanLen)
-	hub.workersHub[name] = workerDirectChan
-	return NewBus(name, workerDirectChan, hub.workersMessages)
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	m, ok := hub.mailboxes[name]
+	if ok {
+		m.reopen()
+	} else {
+		m = hub.newMailbox(name, nil)
+	}
+
+	return &eventBus{
+		name:     name,
+		in:       m.ch,
+		requests: hub.requests,
+		deliver:  hub.deliver,
+	}
+}
+
+// newMailbox creates the mailbox of the worker, if the `ch` is nil, the new channel is made.
+// It must be called with the locked mutex.
+func (hub *Broker) newMailbox(name WorkerName, ch chan *Message) *mailbox {
+	opt, ok := hub.options[name]
+	if !ok {
+		opt = hub.defaultMailbox
+	}
+	if ch == nil {
+		ch = opt.newChan()
+	}
+
+	m := newMailbox(ch, opt)
+	hub.mailboxes[name] = m
+	return m
+}
+
+func (hub *Broker) RemoveWorker(name WorkerName) {
+	hub.mutex.Lock()
+	m, ok := hub.mailboxes[name]
+	delete(hub.mailboxes, name)
+	delete(hub.options, name)
+	hub.mutex.Unlock()
+
+	if ok {
+		m.stop()
+	}
+	hub.requests.FailTarget(name, ErrTargetStopped)
+	hub.topics.RemoveWorker(name)
+}
+
+// WorkerStopped fails the pending requests to the worker with the `ErrTargetStopped`
+// and releases the senders blocked on its full mailbox.
+// New messages to the worker are sent to the dead letters until it is launched again,
+// the messages queued before the stop are kept for the next launch.
+func (hub *Broker) WorkerStopped(name WorkerName) {
+	hub.mutex.RLock()
+	m, ok := hub.mailboxes[name]
+	hub.mutex.RUnlock()
+
+	if ok {
+		m.stop()
+	}
+	hub.requests.FailTarget(name, ErrTargetStopped)
 }
 
 func (hub *Broker) Init() error { return nil }
 
+// Serve blocks until the context is done. The messages are delivered
+// by the senders, so the broker does not need the routing goroutine.
 func (hub *Broker) Serve(ctx context.Context) {
-	for {
-		select {
-		case msg := <-hub.workersMessages:
-			if msg == nil {
+	<-ctx.Done()
+}
+
+// deliver routes the message sent through the bus of the broker.
+func (hub *Broker) deliver(ctx context.Context, msg *Message, wait bool) error {
+	if hub.requests.Resolve(msg) {
+		return nil
+	}
+
+	switch msg.Target {
+	case TargetSubscribe:
+		hub.topics.Subscribe(msg.Sender, msg.Topic)
+		return nil
+
+	case TargetUnsubscribe:
+		hub.topics.Unsubscribe(msg.Sender, msg.Topic)
+		return nil
+
+	case TargetSelfInit:
+		hub.mutex.Lock()
+		defer hub.mutex.Unlock()
+
+		if _, ok := hub.mailboxes[msg.Sender]; ok {
+			return nil
+		}
+		if bus, ok := msg.Data.(chan *Message); ok {
+			hub.newMailbox(msg.Sender, bus)
+		}
+		return nil
+
+	case "":
+		if msg.Topic == "" {
+			hub.observer.MessageDropped(msg.Sender, msg.Target)
+			hub.deadLetter(*msg, msg.Target, DeadLetterUnknownTarget)
+			return fmt.Errorf("%s: %w", msg.Target, ErrUnknownTarget)
+		}
+
+		var err error
+		for _, to := range hub.topics.Subscribers(msg.Topic) {
+			if to == msg.Sender {
 				continue
 			}
+			out := *msg
+			out.Target = to
+			if e := hub.deliverTo(ctx, to, out, wait); e != nil && err == nil {
+				err = e
+			}
+		}
+		return err
+
+	case TargetBroadcast:
+		hub.mutex.RLock()
+		names := make([]WorkerName, 0, len(hub.mailboxes))
+		for name := range hub.mailboxes {
+			names = append(names, name)
+		}
+		hub.mutex.RUnlock()
 
-			switch msg.Target {
-			case TargetSelfInit:
-				_, ok := hub.workersHub[msg.Sender]
-				if ok {
-					continue
-				}
-
-				bus, ok := msg.Data.(chan *Message)
-				if ok {
-					hub.workersHub[msg.Sender] = bus
-				}
-
-			case TargetBroadcast:
-				for to := range hub.workersHub {
-					if to == msg.Sender {
-						continue
-					}
-					b := hub.workersHub[to]
-					go sendMsg(b, *msg)
-
-				}
-			default:
-				if b, ok := hub.workersHub[msg.Target]; ok {
-					go sendMsg(b, *msg)
-				}
+		var err error
+		for _, to := range names {
+			if to == msg.Sender {
+				continue
+			}
+			if e := hub.deliverTo(ctx, to, *msg, wait); e != nil && err == nil {
+				err = e
 			}
+		}
+		return err
+
+	default:
+		return hub.deliverTo(ctx, msg.Target, *msg, wait)
+	}
+}
+
+// deliverTo puts the message into the mailbox of the worker according to its overflow policy.
+// The messages to the stopped worker are not queued, they are sent to the dead letters.
+func (hub *Broker) deliverTo(ctx context.Context, to WorkerName, msg Message, wait bool) error {
+	hub.mutex.RLock()
+	m, ok := hub.mailboxes[to]
+	hub.mutex.RUnlock()
+
+	if !ok {
+		hub.observer.MessageDropped(msg.Sender, to)
+		hub.deadLetter(msg, to, DeadLetterUnknownTarget)
+		return fmt.Errorf("%s: %w", to, ErrUnknownTarget)
+	}
 
-		case <-ctx.Done():
-			return
+	var (
+		evicted []*Message
+		err     error
+	)
+	if m.stopped() {
+		err = ErrTargetStopped
+	} else {
+		hub.observer.MessageSent(msg.Sender, to)
+		evicted, err = m.push(ctx, &msg, wait)
+	}
+
+	for _, old := range evicted {
+		hub.observer.MessageDropped(old.Sender, to)
+		hub.deadLetter(*old, to, DeadLetterMailboxFull)
+		if old.IsRequest() {
+			hub.requests.Fail(old.CorrelationID, fmt.Errorf("%s: %w", to, ErrMailboxFull))
 		}
 	}
+
+	if err != nil {
+		atomic.AddUint64(&m.dropped, 1)
+		hub.observer.MessageDropped(msg.Sender, to)
+		hub.deadLetter(msg, to, deadLetterReason(err))
+		return fmt.Errorf("%s: %w", to, err)
+	}
+
+	hub.observer.MessageDelivered(msg.Sender, to)
+	return nil
 }
 
-func sendMsg(bus chan<- *Message, msg Message) { bus <- &msg }
+// deadLetter records the message which was not delivered to the receiver
+// and passes it to the dead letter handler and worker.
+func (hub *Broker) deadLetter(msg Message, receiver WorkerName, reason DeadLetterReason) {
+	letter := DeadLetter{Time: time.Now(), Reason: reason, Receiver: receiver, Message: msg}
+	config := hub.deadLetters.add(letter)
+
+	if config.Handler != nil {
+		config.Handler(letter)
+	}
+
+	// dead letters to the dead letter worker are not redirected to avoid the loop
+	if config.Worker == "" || config.Worker == receiver {
+		return
+	}
+
+	hub.mutex.RLock()
+	m, ok := hub.mailboxes[config.Worker]
+	hub.mutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	out := &Message{Target: config.Worker, Sender: msg.Sender, Kind: msg.Kind, Data: letter}
+	hub.observer.MessageSent(msg.Sender, config.Worker)
+	evicted, err := m.push(context.Background(), out, false)
+	for _, old := range evicted {
+		hub.observer.MessageDropped(old.Sender, config.Worker)
+	}
+	if err != nil {
+		atomic.AddUint64(&m.dropped, 1)
+		hub.observer.MessageDropped(msg.Sender, config.Worker)
+		return
+	}
+	hub.observer.MessageDelivered(msg.Sender, config.Worker)
+}
 
 // NopBroker is an empty IMQBroker
 type NopBroker struct{}
 
 func (*NopBroker) DefaultBus() SenderBus             { return &NopMailbox{} }
 func (*NopBroker) AddWorker(name WorkerName) Mailbox { return &NopMailbox{} }
+func (*NopBroker) RemoveWorker(name WorkerName)      {}
 func (*NopBroker) Init() error                       { return nil }
 func (*NopBroker) Serve(ctx context.Context)         {}
@@ -1,5 +1,7 @@
 package uwe
 
+import "context"
+
 const (
 	TargetBroadcast = "*"
 	TargetSelfInit  = "self-init"
@@ -18,6 +20,11 @@ type (
 		SelfInit(name WorkerName) Mailbox
 	}
 
+	// MessageSender is a `SenderBus` that can send the prepared message with the metadata.
+	MessageSender interface {
+		SendMessage(msg Message)
+	}
+
 	ReaderBus interface {
 		Messages() <-chan *Message
 	}
@@ -29,9 +36,50 @@ type (
 		Sender WorkerName
 		Kind   MessageKind
 		Data   interface{}
+		// Meta is an optional metadata of the message, for example, the propagated tracing context.
+		// The broker delivers it as is, so the receivers must not modify it.
+		Meta map[string]string
+		// CorrelationID is set by the `Request`, it identifies the request.
+		CorrelationID string
+		// InReplyTo is the `CorrelationID` of the request, to which this message is a reply.
+		InReplyTo string
+		// Topic is a topic to which the message was published.
+		Topic string
 	}
 )
 
+// IsRequest returns `true` if the sender waits for the reply.
+func (m *Message) IsRequest() bool {
+	return m.CorrelationID != ""
+}
+
+// Reply sends the reply to the request through the bus of the receiver, usually it is the worker `Context`.
+// The reply has the same kind as the request. It returns the `ErrNotRequest`, if the message is not a request.
+func (m *Message) Reply(bus SenderBus, data interface{}) error {
+	if !m.IsRequest() {
+		return ErrNotRequest
+	}
+
+	SendMessage(bus, Message{
+		Target:    m.Sender,
+		Kind:      m.Kind,
+		Data:      data,
+		InReplyTo: m.CorrelationID,
+	})
+	return nil
+}
+
+// SendMessage sends the prepared message, the sender is set to the name of the bus owner.
+// It allows passing the message metadata, for example, the tracing context.
+// If the bus does not implement the `MessageSender`, only the target, kind and data of the message are sent.
+func SendMessage(bus SenderBus, msg Message) {
+	if s, ok := bus.(MessageSender); ok {
+		s.SendMessage(msg)
+		return
+	}
+	bus.SendWithKind(msg.Target, msg.Kind, msg.Data)
+}
+
 type eventBus struct {
 	name      WorkerName
 	readOnly  bool
@@ -41,6 +89,10 @@ type eventBus struct {
 	in chan *Message
 	// out is channel for outgoing messages from a worker
 	out chan<- *Message
+	// requests correlates the replies with the requests, it is nil if the broker does not support them.
+	requests *Correlator
+	// deliver passes the message directly to the broker instead of the `out`.
+	deliver func(ctx context.Context, msg *Message, wait bool) error
 }
 
 func NewSenderBus(fromWorker chan<- *Message) SenderBus {
@@ -70,58 +122,111 @@ func NewBus(name WorkerName, toWorker, fromWorker chan *Message) Mailbox {
 
 }
 
+// NewRequestBus returns the `Mailbox` that supports the requests with the passed `Correlator`.
+// It is the same as the `NewBus`, but for the `IMQBroker` that handles the replies.
+func NewRequestBus(name WorkerName, toWorker, fromWorker chan *Message, requests *Correlator) Mailbox {
+	return &eventBus{
+		name:     name,
+		in:       toWorker,
+		out:      fromWorker,
+		requests: requests,
+	}
+}
+
 func (wc *eventBus) SendWithKind(target WorkerName, kind MessageKind, data interface{}) {
+	wc.SendMessage(Message{Target: target, Kind: kind, Data: data})
+}
+
+func (wc *eventBus) SendToMany(kind MessageKind, data interface{}, targets ...WorkerName) {
+	for _, target := range targets {
+		wc.SendMessage(Message{Target: target, Kind: kind, Data: data})
+	}
+}
+
+func (wc *eventBus) Send(target WorkerName, data interface{}) {
+	wc.SendMessage(Message{Target: target, Data: data})
+}
+
+func (wc *eventBus) SendMessage(msg Message) {
 	if wc.readOnly {
 		return
 	}
 
-	wc.out <- &Message{
-		Target: target,
-		Sender: wc.name,
-		Kind:   kind,
-		Data:   data,
-	}
+	msg.Sender = wc.name
+	_ = wc.send(context.Background(), &msg, true)
 }
 
-func (wc *eventBus) SendToMany(kind MessageKind, data interface{}, targets ...WorkerName) {
+func (wc *eventBus) TrySend(msg Message) error {
 	if wc.readOnly {
-		return
+		return nil
 	}
 
-	for _, target := range targets {
-		wc.out <- &Message{
-			Target: target,
-			Sender: wc.name,
-			Kind:   kind,
-			Data:   data,
-		}
+	msg.Sender = wc.name
+	return wc.send(context.Background(), &msg, false)
+}
+
+func (wc *eventBus) RequestMessage(ctx context.Context, msg Message) (*Message, error) {
+	if wc.readOnly || wc.requests == nil {
+		return nil, ErrRequestsNotSupported
 	}
 
+	msg.Sender = wc.name
+	return wc.requests.Request(ctx, msg, func(ctx context.Context, msg *Message) error {
+		return wc.send(ctx, msg, true)
+	})
 }
 
-func (wc *eventBus) Send(target WorkerName, data interface{}) {
-	if wc.readOnly {
+func (wc *eventBus) Publish(topic string, kind MessageKind, data interface{}) {
+	wc.SendMessage(Message{Topic: topic, Kind: kind, Data: data})
+}
+
+func (wc *eventBus) Subscribe(topic string) {
+	wc.sendSubscription(TargetSubscribe, topic)
+}
+
+func (wc *eventBus) Unsubscribe(topic string) {
+	wc.sendSubscription(TargetUnsubscribe, topic)
+}
+
+// sendSubscription passes the subscription change to the broker
+// in the order with other messages sent by the worker.
+func (wc *eventBus) sendSubscription(target WorkerName, topic string) {
+	if wc.readOnly || wc.writeOnly || wc.name == "" {
 		return
 	}
 
-	wc.out <- &Message{
-		Target: target,
-		Sender: wc.name,
-		Data:   data,
-	}
+	_ = wc.send(context.Background(), &Message{Target: target, Sender: wc.name, Topic: topic}, true)
 }
 
 func (wc *eventBus) SelfInit(name WorkerName) Mailbox {
 	wc.name = name
 	wc.writeOnly = false
 
-	wc.out <- &Message{
-		Target: TargetSelfInit,
-		Sender: wc.name,
-		Data:   wc.in,
+	_ = wc.send(context.Background(), &Message{Target: TargetSelfInit, Sender: wc.name, Data: wc.in}, true)
+	return wc
+}
+
+// send passes the message to the broker. If the `wait` is false, it fails instead of blocking.
+func (wc *eventBus) send(ctx context.Context, msg *Message, wait bool) error {
+	if wc.deliver != nil {
+		return wc.deliver(ctx, msg, wait)
 	}
 
-	return wc
+	if !wait {
+		select {
+		case wc.out <- msg:
+			return nil
+		default:
+			return ErrMailboxFull
+		}
+	}
+
+	select {
+	case wc.out <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (wc *eventBus) Messages() <-chan *Message { return wc.in }
@@ -132,7 +237,15 @@ type NopMailbox struct{}
 func (*NopMailbox) Send(WorkerName, interface{})                       {}
 func (*NopMailbox) SendWithKind(WorkerName, MessageKind, interface{})  {}
 func (*NopMailbox) SendToMany(MessageKind, interface{}, ...WorkerName) {}
-func (m *NopMailbox) SelfInit(WorkerName) Mailbox                      { return m }
+func (*NopMailbox) SendMessage(Message)                                {}
+func (*NopMailbox) TrySend(Message) error                              { return nil }
+func (*NopMailbox) Publish(string, MessageKind, interface{})           {}
+func (*NopMailbox) Subscribe(string)                                   {}
+func (*NopMailbox) Unsubscribe(string)                                 {}
+func (*NopMailbox) RequestMessage(context.Context, Message) (*Message, error) {
+	return nil, ErrRequestsNotSupported
+}
+func (m *NopMailbox) SelfInit(WorkerName) Mailbox { return m }
 func (*NopMailbox) Messages() <-chan *Message {
 	c := make(chan *Message)
 
@@ -0,0 +1,219 @@
+package uwe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrMailboxFull is returned when the message is not accepted by the full mailbox of the target worker.
+	ErrMailboxFull = errors.New("mailbox is full")
+	// ErrTrySendNotSupported is returned by the `TrySend` when the bus does not implement the `TrySender`.
+	ErrTrySendNotSupported = errors.New("bus does not support the non-blocking send")
+)
+
+// TrySender is a `SenderBus` that can send without blocking and report the delivery failures.
+type TrySender interface {
+	// TrySend sends the prepared message like the `SendMessage`, but it never blocks.
+	// It returns the `ErrMailboxFull`, if the message is not accepted by the full mailbox,
+	// or the `ErrUnknownTarget` and the `ErrTargetStopped`, if the target can not receive it.
+	TrySend(msg Message) error
+}
+
+// TrySend sends the message through the bus without blocking, see the `TrySender`.
+func TrySend(bus SenderBus, msg Message) error {
+	sender, ok := bus.(TrySender)
+	if !ok {
+		return ErrTrySendNotSupported
+	}
+	return sender.TrySend(msg)
+}
+
+// MailboxOverflow defines the behavior of the broker when the mailbox of the target worker is full.
+type MailboxOverflow int
+
+const (
+	// MailboxBlock blocks the sender until the receiver takes the message from the mailbox.
+	// The message is dropped if the receiver stops while the sender is waiting.
+	// It is the default policy, so no message is lost while the receiver is running.
+	MailboxBlock MailboxOverflow = iota
+	// MailboxDropNewest drops the message which does not fit into the mailbox.
+	// The dropped messages are counted and sent to the dead letters.
+	MailboxDropNewest
+	// MailboxDropOldest drops the oldest message in the mailbox to free the space for the new one.
+	MailboxDropOldest
+	// MailboxFail rejects the message with the `ErrMailboxFull`.
+	// The error is returned only by the `TrySend` and the `Request`,
+	// other methods drop the message.
+	MailboxFail
+)
+
+func (p MailboxOverflow) String() string {
+	switch p {
+	case MailboxBlock:
+		return "block"
+	case MailboxDropNewest:
+		return "drop_newest"
+	case MailboxDropOldest:
+		return "drop_oldest"
+	case MailboxFail:
+		return "fail"
+	default:
+		return fmt.Sprintf("MailboxOverflow(%d)", int(p))
+	}
+}
+
+// ParseMailboxOverflow returns the `MailboxOverflow` by its name:
+// "block" or empty string, "drop_newest", "drop_oldest" and "fail".
+func ParseMailboxOverflow(s string) (MailboxOverflow, error) {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "-", "_") {
+	case "", "block":
+		return MailboxBlock, nil
+	case "drop_newest":
+		return MailboxDropNewest, nil
+	case "drop_oldest":
+		return MailboxDropOldest, nil
+	case "fail":
+		return MailboxFail, nil
+	default:
+		return 0, fmt.Errorf("unknown mailbox overflow(%s)", s)
+	}
+}
+
+// MailboxOption configures the mailbox of the worker.
+// Without this option the worker receives the default mailbox of the broker.
+type MailboxOption struct {
+	// Capacity is a number of messages which the mailbox can hold, values less than 1 are treated as 1.
+	Capacity int
+	// Overflow is a policy applied when the mailbox is full.
+	Overflow MailboxOverflow
+}
+
+func (MailboxOption) thisIsOption() {}
+
+// MailboxConfigurer is an `IMQBroker` that supports the `MailboxOption`.
+// The `Chief` calls the `SetMailboxOption` before the `AddWorker`, if the worker has the option.
+type MailboxConfigurer interface {
+	IMQBroker
+	SetMailboxOption(name WorkerName, opt MailboxOption)
+}
+
+// DropStats is an `IMQBroker` that counts the messages dropped for each worker.
+type DropStats interface {
+	DroppedMessages() map[WorkerName]uint64
+}
+
+// mailbox is a bounded queue of the messages to the worker.
+type mailbox struct {
+	// dropped is accessed atomically, it is the first field to keep the 64-bit alignment.
+	dropped  uint64
+	ch       chan *Message
+	overflow MailboxOverflow
+
+	// mutex serializes the senders of the `MailboxDropOldest` mailbox and guards the `done`.
+	mutex sync.Mutex
+	// done is closed when the worker stops, the blocked senders drop their messages.
+	done chan struct{}
+}
+
+func newMailbox(ch chan *Message, opt MailboxOption) *mailbox {
+	return &mailbox{ch: ch, overflow: opt.Overflow, done: make(chan struct{})}
+}
+
+func (opt MailboxOption) newChan() chan *Message {
+	if opt.Capacity < 1 {
+		opt.Capacity = 1
+	}
+	return make(chan *Message, opt.Capacity)
+}
+
+// stopped reports whether the worker is stopped.
+func (m *mailbox) stopped() bool {
+	select {
+	case <-m.doneChan():
+		return true
+	default:
+		return false
+	}
+}
+
+func (m *mailbox) doneChan() <-chan struct{} {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.done
+}
+
+// stop releases the blocked senders.
+func (m *mailbox) stop() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	select {
+	case <-m.done:
+	default:
+		close(m.done)
+	}
+}
+
+// reopen resets the mailbox on the worker relaunch, the queued messages are kept.
+func (m *mailbox) reopen() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	select {
+	case <-m.done:
+		m.done = make(chan struct{})
+	default:
+	}
+}
+
+// push puts the message into the mailbox according to the overflow policy.
+// If the `wait` is false, the `MailboxBlock` mailbox behaves like the `MailboxFail`.
+// The oldest messages dropped by the `MailboxDropOldest` policy are returned,
+// so the caller handles them without the lock of the mailbox.
+func (m *mailbox) push(ctx context.Context, msg *Message, wait bool) ([]*Message, error) {
+	select {
+	case m.ch <- msg:
+		return nil, nil
+	default:
+	}
+
+	switch {
+	case m.overflow == MailboxBlock && wait:
+		select {
+		case m.ch <- msg:
+			return nil, nil
+		case <-m.doneChan():
+			return nil, ErrTargetStopped
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+	case m.overflow == MailboxDropOldest:
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		var evicted []*Message
+		for {
+			select {
+			case m.ch <- msg:
+				return evicted, nil
+			default:
+			}
+
+			select {
+			case old := <-m.ch:
+				atomic.AddUint64(&m.dropped, 1)
+				evicted = append(evicted, old)
+			default:
+			}
+		}
+
+	default:
+		return nil, ErrMailboxFull
+	}
+}
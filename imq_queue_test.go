@@ -0,0 +1,149 @@
+package uwe
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func receiveAll(mailbox Mailbox) []interface{} {
+	var list []interface{}
+	for {
+		select {
+		case msg := <-mailbox.Messages():
+			list = append(list, msg.Data)
+		default:
+			return list
+		}
+	}
+}
+
+func TestBroker_MailboxOverflow(t *testing.T) {
+	tests := []struct {
+		overflow MailboxOverflow
+		received []interface{}
+		dropped  uint64
+		trySend  error
+	}{
+		{MailboxDropNewest, []interface{}{0, 1}, 3, ErrMailboxFull},
+		{MailboxDropOldest, []interface{}{3, 4}, 3, nil},
+		{MailboxFail, []interface{}{0, 1}, 3, ErrMailboxFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.overflow.String(), func(t *testing.T) {
+			broker := NewBroker(10)
+			broker.SetMailboxOption("receiver", MailboxOption{Capacity: 2, Overflow: tt.overflow})
+			receiver := broker.AddWorker("receiver")
+			bus := broker.DefaultBus()
+
+			for i := 0; i < 4; i++ {
+				bus.Send("receiver", i)
+			}
+			if err := TrySend(bus, Message{Target: "receiver", Data: 4}); !errors.Is(err, tt.trySend) {
+				t.Errorf("TrySend error(%v) is not %v", err, tt.trySend)
+			}
+
+			if depth := broker.MailboxDepths()["receiver"]; depth != 2 {
+				t.Errorf("depth(%d) != 2", depth)
+			}
+			if got := receiveAll(receiver); len(got) != 2 || got[0] != tt.received[0] || got[1] != tt.received[1] {
+				t.Errorf("received(%v) != %v", got, tt.received)
+			}
+			if dropped := broker.DroppedMessages()["receiver"]; dropped != tt.dropped {
+				t.Errorf("dropped(%d) != %d", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestBroker_DefaultMailbox(t *testing.T) {
+	broker := NewBroker(1)
+	receiver := broker.AddWorker("receiver")
+	bus := broker.DefaultBus()
+
+	const count = 10
+	go func() {
+		for i := 0; i < count; i++ {
+			bus.Send("receiver", i)
+		}
+	}()
+
+	// the sender waits for the slow receiver, no message is lost
+	for i := 0; i < count; i++ {
+		time.Sleep(time.Millisecond)
+		select {
+		case msg := <-receiver.Messages():
+			if msg.Data != i {
+				t.Fatalf("message(%v) != %d", msg.Data, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %d was not received", i)
+		}
+	}
+
+	if dropped := broker.DroppedMessages()["receiver"]; dropped != 0 {
+		t.Errorf("dropped(%d) != 0", dropped)
+	}
+}
+
+func TestBroker_MailboxBlock(t *testing.T) {
+	broker := NewBroker(10)
+	broker.SetMailboxOption("receiver", MailboxOption{Capacity: 1, Overflow: MailboxBlock})
+	receiver := broker.AddWorker("receiver")
+	sender := broker.AddWorker("sender")
+
+	const count = 100
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for i := 0; i < count; i++ {
+			sender.Send("receiver", i)
+		}
+	}()
+
+	for i := 0; i < count; i++ {
+		select {
+		case msg := <-receiver.Messages():
+			if msg.Data != i {
+				t.Fatalf("message(%v) != %d, the order is broken", msg.Data, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %d was not received", i)
+		}
+	}
+	<-sent
+
+	sender.Send("receiver", "queued")
+	if err := TrySend(sender, Message{Target: "receiver"}); !errors.Is(err, ErrMailboxFull) {
+		t.Errorf("TrySend error(%v) is not %v", err, ErrMailboxFull)
+	}
+
+	blocked := make(chan struct{})
+	go func() {
+		defer close(blocked)
+		sender.Send("receiver", "blocked")
+	}()
+
+	select {
+	case <-blocked:
+		t.Fatal("sender was not blocked by the full mailbox")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	broker.WorkerStopped("receiver")
+	select {
+	case <-blocked:
+	case <-time.After(time.Second):
+		t.Fatal("sender was not released when the receiver stopped")
+	}
+
+	// the mailbox is kept for the next launch of the worker
+	receiver = broker.AddWorker("receiver")
+	if got := receiveAll(receiver); len(got) != 1 || got[0] != "queued" {
+		t.Errorf("received(%v) != [queued]", got)
+	}
+	if dropped := broker.DroppedMessages()["receiver"]; dropped != 2 {
+		t.Errorf("dropped(%d) != 2", dropped)
+	}
+}
@@ -0,0 +1,165 @@
+package uwe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRequestTimeout is a timeout of the request, if the passed context has no deadline.
+const DefaultRequestTimeout = 30 * time.Second
+
+var (
+	// ErrUnknownTarget is returned when the target of the request is not registered in the broker.
+	ErrUnknownTarget = errors.New("unknown target")
+	// ErrTargetStopped is returned when the target of the request stopped before the reply.
+	ErrTargetStopped = errors.New("target stopped")
+	// ErrRequestTimeout is returned when the reply was not received before the deadline.
+	ErrRequestTimeout = errors.New("request timeout")
+	// ErrRequestsNotSupported is returned when the broker does not support the request/reply pattern.
+	ErrRequestsNotSupported = errors.New("requests are not supported by the broker")
+	// ErrNotRequest is returned on the attempt to reply to the message that is not a request.
+	ErrNotRequest = errors.New("message is not a request")
+)
+
+// RequestBroker is an `IMQBroker` that supports the request/reply pattern.
+// The `Chief` calls the `WorkerStopped` when the worker finishes,
+// so the broker can fail the pending requests to this worker with the `ErrTargetStopped`.
+type RequestBroker interface {
+	IMQBroker
+	WorkerStopped(name WorkerName)
+}
+
+// MessageRequester is a `SenderBus` that can send the prepared message as a request and wait for the reply.
+type MessageRequester interface {
+	RequestMessage(ctx context.Context, msg Message) (*Message, error)
+}
+
+// Request sends the message through the bus and waits for the reply, see the `(*Message).Reply`.
+// It fails with the `ErrUnknownTarget`, `ErrTargetStopped` or `ErrRequestTimeout`,
+// if the context has no deadline, the `DefaultRequestTimeout` is used.
+// It fails with the `ErrRequestsNotSupported`, if the bus does not implement the `MessageRequester`.
+func Request(ctx context.Context, bus SenderBus, target WorkerName, kind MessageKind, data interface{}) (*Message, error) {
+	return RequestMessage(ctx, bus, Message{Target: target, Kind: kind, Data: data})
+}
+
+// RequestMessage sends the prepared message as a request and waits for the reply like the `Request`.
+// It allows passing the message metadata, for example, the tracing context.
+func RequestMessage(ctx context.Context, bus SenderBus, msg Message) (*Message, error) {
+	if r, ok := bus.(MessageRequester); ok {
+		return r.RequestMessage(ctx, msg)
+	}
+	return nil, ErrRequestsNotSupported
+}
+
+// Correlator matches the replies with the pending requests.
+// A broker supports the `Request` with it and the `NewRequestBus`:
+// it must pass each routed message to the `Resolve` before the delivery,
+// and fail the requests which can not be delivered with the `Fail` and `FailTarget`.
+type Correlator struct {
+	mutex   sync.Mutex
+	lastID  uint64
+	pending map[string]*pendingRequest
+}
+
+type pendingRequest struct {
+	target WorkerName
+	reply  chan requestResult
+}
+
+type requestResult struct {
+	msg *Message
+	err error
+}
+
+// NewCorrelator returns the empty `Correlator`.
+func NewCorrelator() *Correlator {
+	return &Correlator{pending: map[string]*pendingRequest{}}
+}
+
+// Request assigns the `CorrelationID` to the message, sends it with the `send` and waits for the reply.
+// If the `ctx` has no deadline, the `DefaultRequestTimeout` is used.
+// The `send` must respect the context, e.g. when the queue of the broker is full.
+func (c *Correlator) Request(ctx context.Context, msg Message, send func(ctx context.Context, msg *Message) error) (*Message, error) {
+	if msg.Target == TargetBroadcast || msg.Target == TargetSelfInit || msg.Target == "" {
+		return nil, fmt.Errorf("%s: %w", msg.Target, ErrUnknownTarget)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+
+	req := &pendingRequest{target: msg.Target, reply: make(chan requestResult, 1)}
+
+	c.mutex.Lock()
+	c.lastID++
+	msg.CorrelationID = strconv.FormatUint(c.lastID, 10)
+	c.pending[msg.CorrelationID] = req
+	c.mutex.Unlock()
+
+	err := send(ctx, &msg)
+	if err == nil {
+		select {
+		case res := <-req.reply:
+			return res.msg, res.err
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	c.remove(msg.CorrelationID)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%s: %w", msg.Target, ErrRequestTimeout)
+	}
+	return nil, err
+}
+
+// Resolve passes the reply to the waiting requester. It returns `true` if the message is a reply,
+// such message must not be delivered to the worker mailbox, even if the request is already gone.
+func (c *Correlator) Resolve(msg *Message) bool {
+	if msg.InReplyTo == "" {
+		return false
+	}
+
+	if req := c.remove(msg.InReplyTo); req != nil {
+		req.reply <- requestResult{msg: msg}
+	}
+	return true
+}
+
+// Fail finishes the pending request with the error.
+func (c *Correlator) Fail(correlationID string, err error) {
+	if req := c.remove(correlationID); req != nil {
+		req.reply <- requestResult{err: err}
+	}
+}
+
+// FailTarget finishes all pending requests to the target with the error.
+func (c *Correlator) FailTarget(target WorkerName, err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for id, req := range c.pending {
+		if req.target == target {
+			delete(c.pending, id)
+			req.reply <- requestResult{err: fmt.Errorf("%s: %w", target, err)}
+		}
+	}
+}
+
+func (c *Correlator) remove(correlationID string) *pendingRequest {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	req, ok := c.pending[correlationID]
+	if !ok {
+		return nil
+	}
+	delete(c.pending, correlationID)
+	return req
+}
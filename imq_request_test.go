@@ -0,0 +1,91 @@
+package uwe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type responderWorker struct{}
+
+func (responderWorker) Run(ctx Context) error {
+	for {
+		select {
+		case msg := <-ctx.Messages():
+			switch msg.Data {
+			case "ignore":
+			case "exit":
+				return nil
+			default:
+				_ = msg.Reply(ctx, msg.Data.(string)+"!")
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+type requesterWorker struct {
+	replies []*Message
+	errs    []error
+}
+
+func (w *requesterWorker) Run(ctx Context) error {
+	request := func(target WorkerName, data string, timeout time.Duration) {
+		reqCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		reply, err := Request(reqCtx, ctx, target, 7, data)
+		w.replies = append(w.replies, reply)
+		w.errs = append(w.errs, err)
+	}
+
+	request("responder", "ping", time.Second)
+	request("responder", "ignore", 50*time.Millisecond)
+	request("missing", "ping", time.Second)
+	request("oneshot", "exit", time.Second)
+	request("oneshot", "ping", time.Second)
+
+	SignalReady(ctx)
+	<-ctx.Done()
+	return nil
+}
+
+func TestBroker_Request(t *testing.T) {
+	requester := &requesterWorker{}
+
+	chief := NewChief()
+	chief.SetEventHandler(func(Event) {})
+	chief.AddWorker("responder", responderWorker{})
+	chief.AddWorker("oneshot", responderWorker{})
+	chief.AddWorker("requester", requester, DependsOn("responder", "oneshot"))
+	chief.SetLocker(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := chief.WaitReady(ctx, "requester"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	runChief(t, chief, 10*time.Second)
+	if len(requester.errs) != 5 {
+		t.Fatalf("requests(%d) != 5", len(requester.errs))
+	}
+
+	reply := requester.replies[0]
+	if requester.errs[0] != nil || reply.Data != "ping!" || reply.Kind != 7 || reply.Sender != "responder" {
+		t.Errorf("unexpected reply: %+v, %v", reply, requester.errs[0])
+	}
+
+	for i, expected := range []error{ErrRequestTimeout, ErrUnknownTarget, ErrTargetStopped, ErrTargetStopped} {
+		if err := requester.errs[i+1]; !errors.Is(err, expected) {
+			t.Errorf("request %d: error(%v) is not %v", i+1, err, expected)
+		}
+	}
+
+	msg := &Message{Data: "not a request"}
+	if err := msg.Reply(&NopMailbox{}, nil); !errors.Is(err, ErrNotRequest) {
+		t.Errorf("reply to the message that is not a request: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package uwe
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// TargetSubscribe is a target of the control message that subscribes the sender to the topic.
+	TargetSubscribe = "topic-subscribe"
+	// TargetUnsubscribe is a target of the control message that unsubscribes the sender from the topic.
+	TargetUnsubscribe = "topic-unsubscribe"
+)
+
+const (
+	// TopicSeparator separates the segments of the topic, e.g. "orders.created".
+	TopicSeparator = "."
+	// TopicAnyWord is a wildcard segment of the topic pattern that matches exactly one segment,
+	// e.g. "orders.*" matches "orders.created", but not "orders" or "orders.created.eu".
+	TopicAnyWord = "*"
+	// TopicAnyWords is a wildcard segment of the topic pattern that matches zero or more segments,
+	// e.g. "orders.#" matches "orders", "orders.created" and "orders.created.eu".
+	TopicAnyWords = "#"
+)
+
+// Publisher is a `SenderBus` that can publish the messages to the topics.
+type Publisher interface {
+	Publish(topic string, kind MessageKind, data interface{})
+}
+
+// Subscriber is a `Mailbox` that can subscribe its owner to the topics.
+type Subscriber interface {
+	Subscribe(topic string)
+	Unsubscribe(topic string)
+}
+
+// Publish sends the message to all workers subscribed to the topic, except the sender.
+// The message is dropped if the bus does not implement the `Publisher`.
+func Publish(bus SenderBus, topic string, kind MessageKind, data interface{}) {
+	if p, ok := bus.(Publisher); ok {
+		p.Publish(topic, kind, data)
+	}
+}
+
+// Subscribe subscribes the owner of the mailbox to the topics matching the pattern,
+// the pattern can contain the `TopicAnyWord` and `TopicAnyWords` wildcards.
+// Subscriptions are kept across the worker restarts and dropped when the worker is removed.
+// The call has no effect if the mailbox does not implement the `Subscriber`.
+func Subscribe(mailbox Mailbox, topic string) {
+	if s, ok := mailbox.(Subscriber); ok {
+		s.Subscribe(topic)
+	}
+}
+
+// Unsubscribe removes the subscription made with the same pattern.
+func Unsubscribe(mailbox Mailbox, topic string) {
+	if s, ok := mailbox.(Subscriber); ok {
+		s.Unsubscribe(topic)
+	}
+}
+
+// MatchTopic reports whether the topic matches the pattern.
+// The pattern can contain the `TopicAnyWord` and `TopicAnyWords` wildcard segments.
+func MatchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+
+	return matchSegments(strings.Split(pattern, TopicSeparator), strings.Split(topic, TopicSeparator))
+}
+
+func matchSegments(pattern, topic []string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case TopicAnyWords:
+			for i := 0; i <= len(topic); i++ {
+				if matchSegments(pattern[1:], topic[i:]) {
+					return true
+				}
+			}
+			return false
+
+		case TopicAnyWord:
+			if len(topic) == 0 {
+				return false
+			}
+
+		default:
+			if len(topic) == 0 || pattern[0] != topic[0] {
+				return false
+			}
+		}
+
+		pattern, topic = pattern[1:], topic[1:]
+	}
+
+	return len(topic) == 0
+}
+
+// Subscriptions holds the topic subscriptions of the workers. The broker updates it
+// on the `TargetSubscribe` and `TargetUnsubscribe` control messages and takes the receivers
+// of the published messages from the `Subscribers`.
+type Subscriptions struct {
+	mutex sync.RWMutex
+	// patterns holds the subscribed workers by the topic pattern.
+	patterns map[string]map[WorkerName]struct{}
+}
+
+// NewSubscriptions returns the empty `Subscriptions`.
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{patterns: map[string]map[WorkerName]struct{}{}}
+}
+
+// Subscribe subscribes the worker to the topics matching the pattern.
+func (s *Subscriptions) Subscribe(name WorkerName, pattern string) {
+	if name == "" || pattern == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	workers, ok := s.patterns[pattern]
+	if !ok {
+		workers = map[WorkerName]struct{}{}
+		s.patterns[pattern] = workers
+	}
+	workers[name] = struct{}{}
+}
+
+// Unsubscribe removes the subscription of the worker to the pattern.
+func (s *Subscriptions) Unsubscribe(name WorkerName, pattern string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.unsubscribe(name, pattern)
+}
+
+// RemoveWorker removes all subscriptions of the worker.
+func (s *Subscriptions) RemoveWorker(name WorkerName) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for pattern := range s.patterns {
+		s.unsubscribe(name, pattern)
+	}
+}
+
+func (s *Subscriptions) unsubscribe(name WorkerName, pattern string) {
+	workers, ok := s.patterns[pattern]
+	if !ok {
+		return
+	}
+
+	delete(workers, name)
+	if len(workers) == 0 {
+		delete(s.patterns, pattern)
+	}
+}
+
+// Subscribers returns the sorted list of the workers subscribed to the topic.
+// The worker subscribed with several matching patterns is listed once.
+func (s *Subscriptions) Subscribers(topic string) []WorkerName {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	set := map[WorkerName]struct{}{}
+	for pattern, workers := range s.patterns {
+		if !MatchTopic(pattern, topic) {
+			continue
+		}
+		for name := range workers {
+			set[name] = struct{}{}
+		}
+	}
+
+	list := make([]WorkerName, 0, len(set))
+	for name := range set {
+		list = append(list, name)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
@@ -0,0 +1,111 @@
+package uwe
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		match   bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.created", "orders.updated", false},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders", false},
+		{"orders.*", "orders.created.eu", false},
+		{"*.created", "orders.created", true},
+		{"orders.*.eu", "orders.created.eu", true},
+		{"orders.#", "orders", true},
+		{"orders.#", "orders.created.eu", true},
+		{"orders.#.eu", "orders.eu", true},
+		{"orders.#.eu", "orders.created.us", false},
+		{"#", "orders.created", true},
+		{"orders", "orders.created", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.topic); got != tt.match {
+			t.Errorf("MatchTopic(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.match)
+		}
+	}
+}
+
+func TestBroker_Publish(t *testing.T) {
+	broker := NewBroker(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.Serve(ctx)
+
+	one := broker.AddWorker("one")
+	all := broker.AddWorker("all")
+	created := broker.AddWorker("created")
+	bus := broker.DefaultBus()
+
+	Subscribe(one, "orders.*")
+	Subscribe(all, "orders.#")
+	Subscribe(all, "orders.created")
+	Subscribe(created, "orders.created")
+
+	// expect receives the messages with the passed topics in any order
+	expect := func(mailbox Mailbox, name WorkerName, topics ...string) {
+		t.Helper()
+		expected := map[string]bool{}
+		for _, topic := range topics {
+			expected[topic] = true
+		}
+
+		for range topics {
+			select {
+			case msg := <-mailbox.Messages():
+				if !expected[msg.Topic] || msg.Target != name || msg.Kind != 3 {
+					t.Errorf("%s: unexpected message: %+v", name, msg)
+				}
+				delete(expected, msg.Topic)
+			case <-time.After(time.Second):
+				t.Errorf("%s: messages with the topics %v were not received", name, expected)
+				return
+			}
+		}
+	}
+	expectNothing := func(mailboxes ...Mailbox) {
+		t.Helper()
+		time.Sleep(50 * time.Millisecond)
+		for _, mailbox := range mailboxes {
+			select {
+			case msg := <-mailbox.Messages():
+				t.Errorf("unexpected message: %+v", msg)
+			default:
+			}
+		}
+	}
+
+	Publish(bus, "orders.created", 3, nil)
+	expect(one, "one", "orders.created")
+	expect(all, "all", "orders.created")
+	expect(created, "created", "orders.created")
+	expectNothing(one, all, created)
+
+	Publish(one, "orders.created.eu", 3, nil)
+	Publish(one, "orders", 3, nil)
+	expect(all, "all", "orders.created.eu", "orders")
+	expectNothing(one, all, created)
+
+	Publish(all, "orders.updated", 3, nil)
+	expect(one, "one", "orders.updated")
+	expectNothing(one, all, created)
+
+	Unsubscribe(all, "orders.#")
+	broker.RemoveWorker("created")
+	Publish(bus, "orders.created", 3, nil)
+	expect(one, "one", "orders.created")
+	expect(all, "all", "orders.created")
+	expectNothing(one, all, created)
+
+	if subscribers := broker.topics.Subscribers("orders.created"); len(subscribers) != 2 {
+		t.Errorf("subscriptions of the removed worker were not dropped: %v", subscribers)
+	}
+}
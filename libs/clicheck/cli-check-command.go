@@ -2,6 +2,7 @@ package clicheck
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/lancer-kit/uwe/v3"
 	"github.com/lancer-kit/uwe/v3/socket"
@@ -34,7 +35,14 @@ func CliCheckCommand(app uwe.AppInfo, workerListProvider func(c *cli.Context) []
 
 			for _, worker := range workerListProvider(c) {
 				state := stateInfo.Workers[worker]
-				if state != uwe.WStateRun {
+				if state == uwe.WStateDegraded {
+					var reason string
+					if health := stateInfo.Details[worker].Health; health != nil {
+						reason = health.Reason
+					}
+					return cli.NewExitError(fmt.Sprintf("%s is degraded: %s", worker, reason), 8)
+				}
+				if !uwe.IsRunning(state) {
 					return cli.NewExitError(worker+" is not active", 7)
 				}
 			}
@@ -5,7 +5,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-// ChiefHandler returns default `EventHandler` that can be used for `Chief.SetEventHandler(...)`.
+// ChiefHandler returns default `EventHandler` that can be used for `Chief.AddEventHandler(...)`.
 func ChiefHandler(entry *logrus.Entry) uwe.EventHandler {
 	return func(event uwe.Event) {
 		var level logrus.Level
@@ -14,14 +14,29 @@ func ChiefHandler(entry *logrus.Entry) uwe.EventHandler {
 			level = logrus.ErrorLevel
 		case uwe.LvlInfo:
 			level = logrus.InfoLevel
+		case uwe.LvlDebug:
+			level = logrus.DebugLevel
 		default:
 			level = logrus.WarnLevel
 		}
-		if event.Fields == nil {
-			event.Fields = map[string]interface{}{}
+
+		// fields are copied, because the event is shared between all handlers
+		fields := make(logrus.Fields, len(event.Fields)+3)
+		for k, v := range event.Fields {
+			fields[k] = v
+		}
+		fields["worker"] = event.Worker
+		fields["kind"] = event.Kind
+		fields["seq"] = event.Seq
+
+		e := entry.WithFields(fields)
+		if !event.Time.IsZero() {
+			e = e.WithTime(event.Time)
+		}
+		if event.Err != nil {
+			e = e.WithError(event.Err)
 		}
-		event.Fields["worker"] = event.Worker
 
-		entry.WithFields(event.Fields).Log(level, event.Message)
+		e.Log(level, event.Message)
 	}
 }
@@ -0,0 +1,403 @@
+package otel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultBrokerChanLen is a buffer size of the broker created by the `Instrument`.
+const defaultBrokerChanLen = 16
+
+// broker wraps the `uwe.IMQBroker` to trace the messages.
+type broker struct {
+	uwe.IMQBroker
+	tracer *Tracer
+
+	mutex sync.Mutex
+	// stop is closed when the `Serve` of the broker finishes to stop the mailbox forwarders.
+	stop chan struct{}
+	// forwarders are the running forwarders by the worker name, the wrapped broker
+	// can keep the mailbox between the worker launches, so its forwarder is reused.
+	// Otherwise, the forwarder of the previous launch is stopped.
+	forwarders map[uwe.WorkerName]forwarder
+	// returned are the messages taken by the stopped forwarders, but not passed to the workers.
+	// The next forwarder of the worker passes them first.
+	returned map[uwe.WorkerName][]*uwe.Message
+}
+
+// forwarder passes the messages from the mailbox of the wrapped broker to the traced mailbox.
+type forwarder struct {
+	from <-chan *uwe.Message
+	to   chan *uwe.Message
+	// stop is closed when the worker is relaunched with another mailbox or removed.
+	stop chan struct{}
+	// done is closed when the forwarder finishes and its messages are returned.
+	done chan struct{}
+}
+
+// WrapBroker returns the `uwe.IMQBroker` which traces the messages sent through the mailboxes.
+// The wrapped broker keeps the support of the `uwe.RemovableBroker`, `uwe.ObservableBroker`, `uwe.RequestBroker`,
+// `uwe.MailboxConfigurer`, `uwe.DeadLetterBroker`, `uwe.MailboxStats` and `uwe.DropStats`.
+func (t *Tracer) WrapBroker(b uwe.IMQBroker) uwe.IMQBroker {
+	return &broker{
+		IMQBroker:  b,
+		tracer:     t,
+		stop:       make(chan struct{}),
+		forwarders: map[uwe.WorkerName]forwarder{},
+		returned:   map[uwe.WorkerName][]*uwe.Message{},
+	}
+}
+
+func (b *broker) Init() error {
+	var stopped []forwarder
+	b.mutex.Lock()
+	select {
+	case <-b.stop:
+		for _, fw := range b.forwarders {
+			stopped = append(stopped, fw)
+		}
+		b.stop = make(chan struct{})
+		b.forwarders = map[uwe.WorkerName]forwarder{}
+	default:
+	}
+	b.mutex.Unlock()
+
+	// the messages returned by the stopped forwarders are passed on the next launch of the workers
+	for _, fw := range stopped {
+		<-fw.done
+	}
+
+	return b.IMQBroker.Init()
+}
+
+func (b *broker) Serve(ctx context.Context) {
+	defer func() {
+		b.mutex.Lock()
+		close(b.stop)
+		b.mutex.Unlock()
+	}()
+
+	b.IMQBroker.Serve(ctx)
+}
+
+func (b *broker) DefaultBus() uwe.SenderBus {
+	return &sender{SenderBus: b.IMQBroker.DefaultBus(), broker: b}
+}
+
+func (b *broker) AddWorker(name uwe.WorkerName) uwe.Mailbox {
+	return b.wrapMailbox(name, b.IMQBroker.AddWorker(name))
+}
+
+// RemoveWorker implements the `uwe.RemovableBroker`, if the wrapped broker supports it.
+// The forwarder of the worker mailbox is stopped in any case. The messages which it did not pass
+// to the worker are sent again through the wrapped broker after the removal, so it handles them
+// as the messages to the unknown worker, e.g. puts them to the dead letters.
+func (b *broker) RemoveWorker(name uwe.WorkerName) {
+	b.mutex.Lock()
+	fw, ok := b.forwarders[name]
+	if ok {
+		close(fw.stop)
+		delete(b.forwarders, name)
+	}
+	b.mutex.Unlock()
+
+	if ok {
+		<-fw.done
+	}
+	returned := b.takeReturned(name)
+
+	if rb, ok := b.IMQBroker.(uwe.RemovableBroker); ok {
+		rb.RemoveWorker(name)
+	}
+
+	bus := b.IMQBroker.DefaultBus()
+	for _, msg := range returned {
+		_ = uwe.TrySend(bus, *msg)
+	}
+}
+
+// SetObserver implements the `uwe.ObservableBroker`, if the wrapped broker supports it.
+func (b *broker) SetObserver(observer uwe.Observer) {
+	if ob, ok := b.IMQBroker.(uwe.ObservableBroker); ok {
+		ob.SetObserver(observer)
+	}
+}
+
+// WorkerStopped implements the `uwe.RequestBroker`, if the wrapped broker supports it.
+func (b *broker) WorkerStopped(name uwe.WorkerName) {
+	if rb, ok := b.IMQBroker.(uwe.RequestBroker); ok {
+		rb.WorkerStopped(name)
+	}
+}
+
+// SetMailboxOption implements the `uwe.MailboxConfigurer`, if the wrapped broker supports it.
+func (b *broker) SetMailboxOption(name uwe.WorkerName, opt uwe.MailboxOption) {
+	if mc, ok := b.IMQBroker.(uwe.MailboxConfigurer); ok {
+		mc.SetMailboxOption(name, opt)
+	}
+}
+
+// DroppedMessages implements the `uwe.DropStats`, if the wrapped broker supports it.
+func (b *broker) DroppedMessages() map[uwe.WorkerName]uint64 {
+	if stats, ok := b.IMQBroker.(uwe.DropStats); ok {
+		return stats.DroppedMessages()
+	}
+	return nil
+}
+
+// SetDeadLetterConfig implements the `uwe.DeadLetterBroker`, if the wrapped broker supports it.
+func (b *broker) SetDeadLetterConfig(config uwe.DeadLetterConfig) {
+	if dlb, ok := b.IMQBroker.(uwe.DeadLetterBroker); ok {
+		dlb.SetDeadLetterConfig(config)
+	}
+}
+
+// DeadLetters implements the `uwe.DeadLetterBroker`, if the wrapped broker supports it.
+func (b *broker) DeadLetters() []uwe.DeadLetter {
+	if dlb, ok := b.IMQBroker.(uwe.DeadLetterBroker); ok {
+		return dlb.DeadLetters()
+	}
+	return nil
+}
+
+// MailboxDepths implements the `uwe.MailboxStats`, if the wrapped broker supports it.
+func (b *broker) MailboxDepths() map[uwe.WorkerName]int {
+	if stats, ok := b.IMQBroker.(uwe.MailboxStats); ok {
+		return stats.MailboxDepths()
+	}
+	return nil
+}
+
+// wrapMailbox returns the mailbox which traces the sent and received messages.
+func (b *broker) wrapMailbox(name uwe.WorkerName, mailbox uwe.Mailbox) uwe.Mailbox {
+	b.mutex.Lock()
+	fw, ok := b.forwarders[name]
+	if !ok || fw.from != mailbox.Messages() {
+		var prev <-chan struct{}
+		if ok {
+			close(fw.stop)
+			prev = fw.done
+		}
+
+		fw = forwarder{
+			from: mailbox.Messages(),
+			to:   make(chan *uwe.Message),
+			stop: make(chan struct{}),
+			done: make(chan struct{}),
+		}
+		b.forwarders[name] = fw
+		go b.forward(b.stop, prev, name, fw)
+	}
+	b.mutex.Unlock()
+
+	return &mailboxWrapper{
+		sender:  sender{SenderBus: mailbox, broker: b, name: name},
+		mailbox: mailbox,
+		in:      fw.to,
+	}
+}
+
+// forward passes the messages to the worker and records the "uwe.imq.receive" span for each of them.
+// The span covers the time from the message arrival to the moment when the worker takes it.
+// It runs until the `Serve` of the broker finishes or the forwarder is stopped,
+// the message which is not passed to the worker at this moment is returned to the broker.
+// The forwarder starts after the previous forwarder of the worker finishes and passes its returned messages first.
+func (b *broker) forward(stop, prev <-chan struct{}, name uwe.WorkerName, fw forwarder) {
+	defer close(fw.done)
+	if prev != nil {
+		<-prev
+	}
+
+	pass := func(msg *uwe.Message, arrivedAt time.Time) bool {
+		select {
+		case fw.to <- msg:
+			b.tracer.receiveSpan(name, msg, arrivedAt).End()
+			return true
+		case <-fw.stop:
+		case <-stop:
+		}
+		return false
+	}
+
+	returned := b.takeReturned(name)
+	for i, msg := range returned {
+		if !pass(msg, time.Now()) {
+			b.returnMessages(name, returned[i:]...)
+			return
+		}
+	}
+
+	for {
+		select {
+		case msg := <-fw.from:
+			if msg == nil {
+				continue
+			}
+			if !pass(msg, time.Now()) {
+				b.returnMessages(name, msg)
+				return
+			}
+
+		case <-fw.stop:
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+// returnMessages keeps the messages which were not passed to the worker for its next forwarder.
+func (b *broker) returnMessages(name uwe.WorkerName, msgs ...*uwe.Message) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.returned[name] = append(b.returned[name], msgs...)
+}
+
+// takeReturned returns the messages which were not passed to the worker by the stopped forwarders.
+func (b *broker) takeReturned(name uwe.WorkerName) []*uwe.Message {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	msgs := b.returned[name]
+	delete(b.returned, name)
+	return msgs
+}
+
+// receiveSpan starts the span of the message delivery as a child of the receiver run span,
+// it is linked to the span of the message sending.
+func (t *Tracer) receiveSpan(name uwe.WorkerName, msg *uwe.Message, arrivedAt time.Time) trace.Span {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithTimestamp(arrivedAt),
+		trace.WithAttributes(
+			attrWorker.String(string(name)),
+			attrSender.String(string(msg.Sender)),
+			attrTarget.String(string(msg.Target)),
+			attrKind.Int(int(msg.Kind)),
+		),
+	}
+
+	if msg.Topic != "" {
+		opts = append(opts, trace.WithAttributes(attrTopic.String(msg.Topic)))
+	}
+
+	remote := trace.SpanContextFromContext(t.MessageContext(context.Background(), msg))
+	if remote.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: remote}))
+	}
+
+	_, span := t.tracer.Start(t.runContext(name), "uwe.imq.receive", opts...)
+	return span
+}
+
+// mailboxWrapper is a traced mailbox of the worker.
+type mailboxWrapper struct {
+	sender
+	mailbox uwe.Mailbox
+	in      chan *uwe.Message
+}
+
+func (m *mailboxWrapper) Messages() <-chan *uwe.Message { return m.in }
+func (m *mailboxWrapper) Subscribe(topic string)        { uwe.Subscribe(m.mailbox, topic) }
+func (m *mailboxWrapper) Unsubscribe(topic string)      { uwe.Unsubscribe(m.mailbox, topic) }
+
+// sender is a bus which traces the sent messages.
+type sender struct {
+	uwe.SenderBus
+	broker *broker
+	name   uwe.WorkerName
+}
+
+func (s *sender) Send(target uwe.WorkerName, data interface{}) {
+	s.SendMessage(uwe.Message{Target: target, Data: data})
+}
+
+func (s *sender) SendWithKind(target uwe.WorkerName, kind uwe.MessageKind, data interface{}) {
+	s.SendMessage(uwe.Message{Target: target, Kind: kind, Data: data})
+}
+
+func (s *sender) SendToMany(kind uwe.MessageKind, data interface{}, targets ...uwe.WorkerName) {
+	for _, target := range targets {
+		s.SendMessage(uwe.Message{Target: target, Kind: kind, Data: data})
+	}
+}
+
+func (s *sender) Publish(topic string, kind uwe.MessageKind, data interface{}) {
+	s.SendMessage(uwe.Message{Topic: topic, Kind: kind, Data: data})
+}
+
+func (s *sender) SendMessage(msg uwe.Message) {
+	_ = s.send("uwe.imq.send", msg, func(msg uwe.Message) error {
+		uwe.SendMessage(s.SenderBus, msg)
+		return nil
+	})
+}
+
+func (s *sender) TrySend(msg uwe.Message) error {
+	return s.send("uwe.imq.send", msg, func(msg uwe.Message) error {
+		return uwe.TrySend(s.SenderBus, msg)
+	})
+}
+
+// RequestMessage records the "uwe.imq.request" span, which covers the waiting for the reply.
+func (s *sender) RequestMessage(ctx context.Context, msg uwe.Message) (*uwe.Message, error) {
+	var reply *uwe.Message
+	err := s.send("uwe.imq.request", msg, func(msg uwe.Message) error {
+		var err error
+		reply, err = uwe.RequestMessage(ctx, s.SenderBus, msg)
+		return err
+	})
+	return reply, err
+}
+
+// send records the span with the name as a child of the sender run span
+// and propagates its context inside the `Message.Meta`.
+func (s *sender) send(spanName string, msg uwe.Message, send func(msg uwe.Message) error) error {
+	tracer := s.broker.tracer
+	ctx, span := tracer.tracer.Start(tracer.runContext(s.name), spanName,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attrSender.String(string(s.name)),
+			attrTarget.String(string(msg.Target)),
+			attrKind.Int(int(msg.Kind)),
+		),
+	)
+	defer span.End()
+
+	if msg.Topic != "" {
+		span.SetAttributes(attrTopic.String(msg.Topic))
+	}
+
+	meta := make(map[string]string, len(msg.Meta)+2)
+	for k, v := range msg.Meta {
+		meta[k] = v
+	}
+	tracer.propagator.Inject(ctx, propagation.MapCarrier(meta))
+	msg.Meta = meta
+
+	err := send(msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+func (s *sender) SelfInit(name uwe.WorkerName) uwe.Mailbox {
+	return s.broker.wrapMailbox(name, s.SenderBus.SelfInit(name))
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,19 @@
+module github.com/lancer-kit/uwe/libs/otel
+
+go 1.17
+
+require (
+	github.com/lancer-kit/uwe/v3 v3.0.0
+	github.com/sheb-gregor/sam v1.0.0
+	go.opentelemetry.io/otel v1.10.0
+	go.opentelemetry.io/otel/sdk v1.10.0
+	go.opentelemetry.io/otel/trace v1.10.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+)
+
+replace github.com/lancer-kit/uwe/v3 => ../../
@@ -0,0 +1,192 @@
+package otel
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+	"github.com/sheb-gregor/sam"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is a name of the tracer used for the spans.
+const InstrumentationName = "github.com/lancer-kit/uwe/libs/otel"
+
+const (
+	attrWorker   = attribute.Key("uwe.worker")
+	attrSender   = attribute.Key("uwe.imq.sender")
+	attrTarget   = attribute.Key("uwe.imq.target")
+	attrKind     = attribute.Key("uwe.imq.kind")
+	attrTopic    = attribute.Key("uwe.imq.topic")
+	attrPanicked = attribute.Key("uwe.worker.panicked")
+)
+
+// Tracer records the workers lifecycle and the IMQ messages as the OpenTelemetry spans.
+// It receives the lifecycle notifications as the `uwe.Observer`:
+//   - "uwe.worker.init" span covers the `Init` of the worker;
+//   - "uwe.worker.run" span covers each `Run` of the worker, state changes
+//     and the `Chief` events of the worker are added to it as the span events;
+//   - "uwe.worker.restart" and "uwe.worker.stop" spans mark the restarts and the stop of the worker.
+//
+// Messages sent through the mailboxes of the wrapped broker produce the "uwe.imq.send" spans,
+// the context of which is propagated inside the `Message.Meta`.
+// Each delivery produces the "uwe.imq.receive" span linked to the "uwe.imq.send" span.
+type Tracer struct {
+	uwe.NopObserver
+
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+
+	mutex sync.RWMutex
+	runs  map[uwe.WorkerName]trace.Span
+}
+
+// Option configures the `Tracer`.
+type Option func(t *Tracer)
+
+// WithPropagator replaces the default W3C Trace Context propagator
+// used to pass the tracing context inside the messages.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(t *Tracer) { t.propagator = propagator }
+}
+
+// New returns the `Tracer` that creates spans with the passed `TracerProvider`.
+func New(provider trace.TracerProvider, opts ...Option) *Tracer {
+	t := &Tracer{
+		tracer:     provider.Tracer(InstrumentationName),
+		propagator: propagation.TraceContext{},
+		runs:       map[uwe.WorkerName]trace.Span{},
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Instrument registers the `Tracer` as the observer of the `Chief`
+// and replaces the `IMQBroker` of the `Chief` by the wrapped `broker`.
+// If the `broker` is nil, the `uwe.NewBroker` is used.
+func (t *Tracer) Instrument(chief uwe.Chief, broker uwe.IMQBroker) uwe.Chief {
+	if broker == nil {
+		broker = uwe.NewBroker(defaultBrokerChanLen)
+	}
+
+	return chief.AddObserver(t).UseCustomIMQBroker(t.WrapBroker(broker))
+}
+
+// EventHandler returns the `uwe.EventHandler` that adds the `Chief` events
+// to the current run span of the worker and passes them to the `next` handler.
+func (t *Tracer) EventHandler(next uwe.EventHandler) uwe.EventHandler {
+	return func(event uwe.Event) {
+		if span := t.runSpan(event.Worker); span != nil {
+			attrs := make([]attribute.KeyValue, 0, len(event.Fields)+1)
+			attrs = append(attrs, attribute.String("level", string(event.Level)))
+			for k, v := range event.Fields {
+				attrs = append(attrs, attribute.String(k, toString(v)))
+			}
+			span.AddEvent(event.Message, trace.WithAttributes(attrs...))
+		}
+
+		if next != nil {
+			next(event)
+		}
+	}
+}
+
+// WorkerStateChanged implements the `uwe.Observer`.
+func (t *Tracer) WorkerStateChanged(name uwe.WorkerName, from, to sam.State) {
+	switch {
+	case to == uwe.WStateRun && from != uwe.WStateDegraded:
+		_, span := t.tracer.Start(context.Background(), "uwe.worker.run",
+			trace.WithAttributes(attrWorker.String(string(name))))
+
+		t.mutex.Lock()
+		t.runs[name] = span
+		t.mutex.Unlock()
+
+	case to == uwe.WStateStopped:
+		t.instantSpan(name, "uwe.worker.stop")
+
+	default:
+		if span := t.runSpan(name); span != nil {
+			span.AddEvent("state changed", trace.WithAttributes(
+				attribute.String("from", string(from)), attribute.String("to", string(to))))
+		}
+	}
+}
+
+// WorkerInitialized implements the `uwe.Observer`.
+func (t *Tracer) WorkerInitialized(name uwe.WorkerName, duration time.Duration, err error) {
+	now := time.Now()
+	_, span := t.tracer.Start(context.Background(), "uwe.worker.init",
+		trace.WithTimestamp(now.Add(-duration)),
+		trace.WithAttributes(attrWorker.String(string(name))))
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(now))
+}
+
+// WorkerRunFinished implements the `uwe.Observer`.
+func (t *Tracer) WorkerRunFinished(name uwe.WorkerName, _ time.Duration, err error, panicked bool) {
+	t.mutex.Lock()
+	span, ok := t.runs[name]
+	delete(t.runs, name)
+	t.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(attrPanicked.Bool(panicked))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// WorkerRestarted implements the `uwe.Observer`.
+func (t *Tracer) WorkerRestarted(name uwe.WorkerName) {
+	t.instantSpan(name, "uwe.worker.restart")
+}
+
+// instantSpan records the span of the worker lifecycle event without duration.
+func (t *Tracer) instantSpan(name uwe.WorkerName, spanName string) {
+	_, span := t.tracer.Start(context.Background(), spanName,
+		trace.WithAttributes(attrWorker.String(string(name))))
+	span.End()
+}
+
+// runSpan returns the span of the current run of the worker.
+func (t *Tracer) runSpan(name uwe.WorkerName) trace.Span {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.runs[name]
+}
+
+// runContext returns the context with the span of the current run of the worker.
+func (t *Tracer) runContext(name uwe.WorkerName) context.Context {
+	ctx := context.Background()
+	if span := t.runSpan(name); span != nil {
+		ctx = trace.ContextWithSpan(ctx, span)
+	}
+	return ctx
+}
+
+// MessageContext returns the context with the tracing context extracted from the message.
+// It can be used by the worker to continue the trace of the message processing.
+func (t *Tracer) MessageContext(ctx context.Context, msg *uwe.Message) context.Context {
+	if msg == nil || len(msg.Meta) == 0 {
+		return ctx
+	}
+	return t.propagator.Extract(ctx, propagation.MapCarrier(msg.Meta))
+}
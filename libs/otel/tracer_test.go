@@ -0,0 +1,284 @@
+package otel
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type pingWorker struct {
+	runs int32
+}
+
+func (w *pingWorker) Init() error { return nil }
+
+func (w *pingWorker) Run(ctx uwe.Context) error {
+	if atomic.AddInt32(&w.runs, 1) == 1 {
+		return errors.New("failed")
+	}
+
+	ctx.Send("pong", "ping")
+	<-ctx.Done()
+	return nil
+}
+
+type pongWorker struct {
+	received atomic.Value
+}
+
+func (w *pongWorker) Run(ctx uwe.Context) error {
+	for {
+		select {
+		case msg := <-ctx.Messages():
+			w.received.Store(msg)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracer := New(provider)
+
+	pong := &pongWorker{}
+	chief := uwe.NewChief()
+	chief.SetEventHandler(tracer.EventHandler(nil))
+	chief.AddWorker("ping", &pingWorker{}, uwe.Restart)
+	chief.AddWorker("pong", pong)
+	tracer.Instrument(chief, nil)
+	chief.SetLocker(func() {
+		for pong.received.Load() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		chief.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("chief was not stopped in time")
+	}
+
+	msg := pong.received.Load().(*uwe.Message)
+	if msg.Meta["traceparent"] == "" {
+		t.Errorf("trace context was not propagated: %v", msg.Meta)
+	}
+
+	spans := map[string][]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = append(spans[span.Name], span)
+	}
+
+	for name, count := range map[string]int{
+		"uwe.worker.init":    1,
+		"uwe.worker.run":     3,
+		"uwe.worker.restart": 1,
+		"uwe.worker.stop":    2,
+		"uwe.imq.send":       1,
+		"uwe.imq.receive":    1,
+	} {
+		if len(spans[name]) != count {
+			t.Errorf("%s spans(%d) != %d", name, len(spans[name]), count)
+		}
+	}
+	if t.Failed() {
+		return
+	}
+
+	send, receive := spans["uwe.imq.send"][0], spans["uwe.imq.receive"][0]
+	if send.SpanKind != trace.SpanKindProducer || receive.SpanKind != trace.SpanKindConsumer {
+		t.Errorf("unexpected span kinds: send(%s) receive(%s)", send.SpanKind, receive.SpanKind)
+	}
+	if len(receive.Links) != 1 ||
+		receive.Links[0].SpanContext.TraceID() != send.SpanContext.TraceID() ||
+		receive.Links[0].SpanContext.SpanID() != send.SpanContext.SpanID() {
+		t.Errorf("receive span is not linked to the send span: %+v", receive.Links)
+	}
+
+	runs := map[trace.SpanID]tracetest.SpanStub{}
+	for _, run := range spans["uwe.worker.run"] {
+		runs[run.SpanContext.SpanID()] = run
+	}
+	if _, ok := runs[send.Parent.SpanID()]; !ok {
+		t.Error("send span is not a child of the sender run span")
+	}
+	if _, ok := runs[receive.Parent.SpanID()]; !ok {
+		t.Error("receive span is not a child of the receiver run span")
+	}
+
+	var failed int
+	for _, run := range runs {
+		if run.Status.Description == "failed" {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("failed run spans(%d) != 1", failed)
+	}
+}
+
+type echoWorker struct{}
+
+func (echoWorker) Init() error { return nil }
+
+func (echoWorker) Run(ctx uwe.Context) error {
+	for {
+		select {
+		case msg := <-ctx.Messages():
+			_ = msg.Reply(ctx, msg.Meta["traceparent"])
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+type requestWorker struct {
+	reply atomic.Value
+}
+
+func (w *requestWorker) Init() error { return nil }
+
+func (w *requestWorker) Run(ctx uwe.Context) error {
+	reply, err := uwe.Request(ctx, ctx, "echo", 1, "ping")
+	if err != nil {
+		return err
+	}
+
+	w.reply.Store(reply)
+	<-ctx.Done()
+	return nil
+}
+
+func TestTracer_Request(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	requester := &requestWorker{}
+	chief := uwe.NewChief()
+	chief.SetEventHandler(func(uwe.Event) {})
+	chief.AddWorker("echo", echoWorker{})
+	chief.AddWorker("requester", requester, uwe.DependsOn("echo"))
+	tracer.Instrument(chief, nil)
+	chief.SetLocker(func() {
+		for requester.reply.Load() == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		chief.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("chief was not stopped in time")
+	}
+
+	var request *tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "uwe.imq.request" {
+			span := span
+			request = &span
+		}
+	}
+	if request == nil {
+		t.Fatal("request span was not recorded")
+	}
+
+	// the receiver got the trace context of the request span
+	reply := requester.reply.Load().(*uwe.Message)
+	if traceparent, _ := reply.Data.(string); !strings.Contains(traceparent, request.SpanContext.SpanID().String()) {
+		t.Errorf("trace context of the request span was not propagated: %v", reply.Data)
+	}
+	if request.SpanKind != trace.SpanKindProducer || request.EndTime.Before(request.StartTime) {
+		t.Errorf("unexpected request span: %+v", request)
+	}
+}
+
+// relaunchBroker returns the new mailbox on each launch of the worker.
+type relaunchBroker struct {
+	uwe.NopBroker
+	inboxes []chan *uwe.Message
+	// sent are the messages sent through the default bus.
+	sent chan *uwe.Message
+}
+
+func (b *relaunchBroker) AddWorker(name uwe.WorkerName) uwe.Mailbox {
+	in := make(chan *uwe.Message)
+	b.inboxes = append(b.inboxes, in)
+	return uwe.NewBus(name, in, make(chan *uwe.Message))
+}
+
+func (b *relaunchBroker) DefaultBus() uwe.SenderBus {
+	return uwe.NewBus("", nil, b.sent)
+}
+
+func TestBroker_Forwarders(t *testing.T) {
+	b := New(sdktrace.NewTracerProvider()).WrapBroker(&relaunchBroker{}).(*broker)
+	stopped := func(fw forwarder) bool {
+		select {
+		case <-fw.stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+	b.AddWorker("worker")
+	first := b.forwarders["worker"]
+	b.AddWorker("worker")
+	second := b.forwarders["worker"]
+	if !stopped(first) || stopped(second) {
+		t.Error("forwarder of the previous launch must be stopped on the relaunch")
+	}
+
+	b.RemoveWorker("worker")
+	if !stopped(second) || len(b.forwarders) != 0 {
+		t.Error("forwarder must be stopped on the worker removal")
+	}
+}
+
+func TestBroker_MessageInTransit(t *testing.T) {
+	wrapped := &relaunchBroker{sent: make(chan *uwe.Message, 1)}
+	b := New(sdktrace.NewTracerProvider()).WrapBroker(wrapped).(*broker)
+
+	// the message is taken by the forwarder, but the worker does not read it
+	b.AddWorker("worker")
+	wrapped.inboxes[0] <- &uwe.Message{Target: "worker", Data: 1}
+
+	mailbox := b.AddWorker("worker")
+	select {
+	case msg := <-mailbox.Messages():
+		if msg.Data != 1 {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message of the stopped forwarder was not passed after the relaunch")
+	}
+
+	wrapped.inboxes[1] <- &uwe.Message{Target: "worker", Data: 2}
+	b.RemoveWorker("worker")
+	select {
+	case msg := <-wrapped.sent:
+		if msg.Data != 2 || msg.Target != "worker" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	default:
+		t.Fatal("message of the removed worker was not sent back to the wrapped broker")
+	}
+}
@@ -0,0 +1,192 @@
+package prometheus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/sheb-gregor/sam"
+)
+
+// UnknownWorker is a value of the "worker" label of the dropped messages to the targets which are not the workers,
+// it keeps the number of the label values bounded.
+const UnknownWorker = "_unknown"
+
+// workerStates is a list of states exported by the state gauge.
+var workerStates = []sam.State{
+	uwe.WStateNew,
+	uwe.WStateInitialized,
+	uwe.WStateRun,
+	uwe.WStateReady,
+	uwe.WStateDegraded,
+	uwe.WStateStopped,
+	uwe.WStateFailed,
+}
+
+// Collector exports the metrics of the `Chief` workers and the IMQ broker.
+// It implements the `prometheus.Collector` and receives the notifications as the `uwe.Observer`.
+type Collector struct {
+	chief uwe.Chief
+
+	stateDesc        *prom.Desc
+	mailboxDepthDesc *prom.Desc
+
+	restarts     *prom.CounterVec
+	panics       *prom.CounterVec
+	errors       *prom.CounterVec
+	initDuration *prom.HistogramVec
+	runDuration  *prom.HistogramVec
+
+	messagesSent      *prom.CounterVec
+	messagesDelivered *prom.CounterVec
+	messagesDropped   *prom.CounterVec
+
+	// workers are the names of the workers seen by the `WorkerStateChanged`.
+	workersMutex sync.RWMutex
+	workers      map[uwe.WorkerName]struct{}
+}
+
+// NewCollector returns the `Collector` registered as the observer of the `Chief`.
+// The collector must be registered in the prometheus registry by the caller:
+//
+//	prometheus.MustRegister(uweprom.NewCollector(chief, "app"))
+func NewCollector(chief uwe.Chief, namespace string) *Collector {
+	workerCounter := func(name, help string) *prom.CounterVec {
+		return prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace, Subsystem: "worker", Name: name, Help: help,
+		}, []string{"worker"})
+	}
+	workerHistogram := func(name, help string) *prom.HistogramVec {
+		return prom.NewHistogramVec(prom.HistogramOpts{
+			Namespace: namespace, Subsystem: "worker", Name: name, Help: help,
+			Buckets: prom.ExponentialBuckets(0.001, 4, 12),
+		}, []string{"worker"})
+	}
+	imqCounter := func(name, help, label string) *prom.CounterVec {
+		return prom.NewCounterVec(prom.CounterOpts{
+			Namespace: namespace, Subsystem: "imq", Name: name, Help: help,
+		}, []string{label})
+	}
+
+	c := &Collector{
+		chief:   chief,
+		workers: map[uwe.WorkerName]struct{}{},
+
+		stateDesc: prom.NewDesc(prom.BuildFQName(namespace, "worker", "state"),
+			"Current state of the worker, the gauge is 1 for the current state and 0 for others.",
+			[]string{"worker", "state"}, nil),
+		mailboxDepthDesc: prom.NewDesc(prom.BuildFQName(namespace, "imq", "mailbox_depth"),
+			"Number of messages waiting for the delivery to the worker.",
+			[]string{"worker"}, nil),
+
+		restarts:     workerCounter("restarts_total", "Number of the worker restarts after failures."),
+		panics:       workerCounter("panics_total", "Number of the worker panics."),
+		errors:       workerCounter("errors_total", "Number of errors returned by the worker Init or Run."),
+		initDuration: workerHistogram("init_duration_seconds", "Duration of the worker Init."),
+		runDuration:  workerHistogram("run_duration_seconds", "Duration of the worker Run."),
+
+		messagesSent:      imqCounter("messages_sent_total", "Number of messages sent by the worker and accepted for the delivery.", "sender"),
+		messagesDelivered: imqCounter("messages_delivered_total", "Number of messages delivered to the worker.", "worker"),
+		messagesDropped:   imqCounter("messages_dropped_total", "Number of messages dropped by the broker.", "worker"),
+	}
+
+	chief.AddObserver(c)
+	return c
+}
+
+func (c *Collector) vectors() []prom.Collector {
+	return []prom.Collector{
+		c.restarts, c.panics, c.errors, c.initDuration, c.runDuration,
+		c.messagesSent, c.messagesDelivered, c.messagesDropped,
+	}
+}
+
+// Describe implements the `prometheus.Collector`.
+func (c *Collector) Describe(ch chan<- *prom.Desc) {
+	ch <- c.stateDesc
+	ch <- c.mailboxDepthDesc
+	for _, v := range c.vectors() {
+		v.Describe(ch)
+	}
+}
+
+// Collect implements the `prometheus.Collector`.
+func (c *Collector) Collect(ch chan<- prom.Metric) {
+	for name, current := range c.chief.GetWorkersStates() {
+		for _, state := range workerStates {
+			var value float64
+			if state == current {
+				value = 1
+			}
+			ch <- prom.MustNewConstMetric(c.stateDesc, prom.GaugeValue, value, string(name), string(state))
+		}
+	}
+
+	for name, depth := range c.chief.GetMailboxDepths() {
+		ch <- prom.MustNewConstMetric(c.mailboxDepthDesc, prom.GaugeValue, float64(depth), string(name))
+	}
+
+	for _, v := range c.vectors() {
+		v.Collect(ch)
+	}
+}
+
+// WorkerStateChanged implements the `uwe.Observer`.
+// The state gauge is collected from the `Chief` on each scrape, so only the name of the worker is recorded.
+func (c *Collector) WorkerStateChanged(name uwe.WorkerName, _, _ sam.State) {
+	c.workersMutex.Lock()
+	c.workers[name] = struct{}{}
+	c.workersMutex.Unlock()
+}
+
+// workerLabel returns the name of the worker or the `UnknownWorker`, if the worker was never seen.
+func (c *Collector) workerLabel(name uwe.WorkerName) string {
+	c.workersMutex.RLock()
+	defer c.workersMutex.RUnlock()
+
+	if _, ok := c.workers[name]; !ok {
+		return UnknownWorker
+	}
+	return string(name)
+}
+
+// WorkerInitialized implements the `uwe.Observer`.
+func (c *Collector) WorkerInitialized(name uwe.WorkerName, duration time.Duration, err error) {
+	c.initDuration.WithLabelValues(string(name)).Observe(duration.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(string(name)).Inc()
+	}
+}
+
+// WorkerRunFinished implements the `uwe.Observer`.
+func (c *Collector) WorkerRunFinished(name uwe.WorkerName, duration time.Duration, err error, panicked bool) {
+	c.runDuration.WithLabelValues(string(name)).Observe(duration.Seconds())
+	switch {
+	case panicked:
+		c.panics.WithLabelValues(string(name)).Inc()
+	case err != nil:
+		c.errors.WithLabelValues(string(name)).Inc()
+	}
+}
+
+// WorkerRestarted implements the `uwe.Observer`.
+func (c *Collector) WorkerRestarted(name uwe.WorkerName) {
+	c.restarts.WithLabelValues(string(name)).Inc()
+}
+
+// MessageSent implements the `uwe.Observer`.
+func (c *Collector) MessageSent(from, _ uwe.WorkerName) {
+	c.messagesSent.WithLabelValues(string(from)).Inc()
+}
+
+// MessageDelivered implements the `uwe.Observer`.
+func (c *Collector) MessageDelivered(_, to uwe.WorkerName) {
+	c.messagesDelivered.WithLabelValues(string(to)).Inc()
+}
+
+// MessageDropped implements the `uwe.Observer`.
+// The messages to the unknown targets are counted with the `UnknownWorker` label.
+func (c *Collector) MessageDropped(_, to uwe.WorkerName) {
+	c.messagesDropped.WithLabelValues(c.workerLabel(to)).Inc()
+}
@@ -0,0 +1,125 @@
+package prometheus
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type flakyWorker struct {
+	runs int32
+}
+
+func (w *flakyWorker) Run(ctx uwe.Context) error {
+	if atomic.AddInt32(&w.runs, 1) == 1 {
+		ctx.Send("ghost", "hello")
+		return errors.New("failed")
+	}
+
+	ctx.Send("sink", "hello")
+	<-ctx.Done()
+	return nil
+}
+
+type sinkWorker struct {
+	received int32
+}
+
+func (w *sinkWorker) Run(ctx uwe.Context) error {
+	for {
+		select {
+		case <-ctx.Messages():
+			atomic.AddInt32(&w.received, 1)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func TestCollector(t *testing.T) {
+	sink := &sinkWorker{}
+	chief := uwe.NewChief()
+	chief.SetEventHandler(func(uwe.Event) {})
+	chief.AddWorker("flaky", &flakyWorker{}, uwe.Restart)
+	chief.AddWorker("sink", sink)
+
+	collector := NewCollector(chief, "app")
+	registry := prom.NewPedanticRegistry()
+	registry.MustRegister(collector)
+
+	var restarts, errs, sent, dropped float64
+	var states map[string]float64
+	var depths, droppedSeries int
+	chief.SetLocker(func() {
+		for atomic.LoadInt32(&sink.received) == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		restarts = testutil.ToFloat64(collector.restarts.WithLabelValues("flaky"))
+		errs = testutil.ToFloat64(collector.errors.WithLabelValues("flaky"))
+		sent = testutil.ToFloat64(collector.messagesSent.WithLabelValues("flaky"))
+		droppedSeries = testutil.CollectAndCount(collector.messagesDropped)
+		dropped = testutil.ToFloat64(collector.messagesDropped.WithLabelValues(UnknownWorker))
+		states = workerStateMetrics(t, registry, "sink")
+		depths, _ = testutil.GatherAndCount(registry, "app_imq_mailbox_depth")
+	})
+
+	done := make(chan struct{})
+	go func() {
+		chief.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("chief was not stopped in time")
+	}
+
+	if restarts != 1 || errs != 1 {
+		t.Errorf("restarts(%v) != 1 or errors(%v) != 1", restarts, errs)
+	}
+	if sent != 1 || dropped != 1 {
+		t.Errorf("sent(%v) != 1 or dropped(%v) != 1", sent, dropped)
+	}
+	if droppedSeries != 1 {
+		t.Errorf("dropped message series(%d) != 1", droppedSeries)
+	}
+	if len(states) != len(workerStates) || states[string(uwe.WStateRun)] != 1 {
+		t.Errorf("unexpected state metrics: %v", states)
+	}
+	if depths != 2 {
+		t.Errorf("mailbox depth metrics(%d) != 2", depths)
+	}
+}
+
+// workerStateMetrics returns the values of the state gauge of the worker by the state label.
+func workerStateMetrics(t *testing.T, registry *prom.Registry, worker string) map[string]float64 {
+	families, err := registry.Gather()
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+
+	r := map[string]float64{}
+	for _, family := range families {
+		if family.GetName() != "app_worker_state" {
+			continue
+		}
+
+		for _, m := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["worker"] == worker {
+				r[labels["state"]] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	return r
+}
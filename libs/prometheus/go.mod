@@ -0,0 +1,23 @@
+module github.com/lancer-kit/uwe/libs/prometheus
+
+go 1.17
+
+require (
+	github.com/lancer-kit/uwe/v3 v3.0.0
+	github.com/prometheus/client_golang v1.12.2
+	github.com/sheb-gregor/sam v1.0.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/prometheus/client_model v0.2.0 // indirect
+	github.com/prometheus/common v0.32.1 // indirect
+	github.com/prometheus/procfs v0.7.3 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+)
+
+replace github.com/lancer-kit/uwe/v3 => ../../
@@ -0,0 +1,9 @@
+module github.com/lancer-kit/uwe/libs/sloghook
+
+go 1.21
+
+require github.com/lancer-kit/uwe/v3 v3.0.0
+
+require github.com/sheb-gregor/sam v1.0.0 // indirect
+
+replace github.com/lancer-kit/uwe/v3 => ../../
@@ -0,0 +1,73 @@
+package sloghook
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+)
+
+// ChiefHandler returns default `EventHandler` that can be used for `Chief.AddEventHandler(...)`.
+// The worker name and the event kind are passed as the "worker" and "kind" attributes,
+// the `Fields` of the event follow them in the sorted order.
+// The record keeps the time when the event was emitted.
+func ChiefHandler(logger *slog.Logger) uwe.EventHandler {
+	return func(event uwe.Event) {
+		level := Level(event.Level)
+
+		ctx := context.Background()
+		if !logger.Enabled(ctx, level) {
+			return
+		}
+
+		attrs := make([]slog.Attr, 0, len(event.Fields)+4)
+		attrs = append(attrs,
+			slog.String("worker", string(event.Worker)),
+			slog.String("kind", string(event.Kind)),
+		)
+		if event.Seq > 0 {
+			attrs = append(attrs, slog.Uint64("seq", event.Seq))
+		}
+
+		keys := make([]string, 0, len(event.Fields))
+		for k := range event.Fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k == "error" && event.Err != nil {
+				continue
+			}
+			attrs = append(attrs, slog.Any(k, event.Fields[k]))
+		}
+		if event.Err != nil {
+			attrs = append(attrs, slog.String("error", event.Err.Error()))
+		}
+
+		t := event.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+
+		record := slog.NewRecord(t, level, event.Message, 0)
+		record.AddAttrs(attrs...)
+		_ = logger.Handler().Handle(ctx, record)
+	}
+}
+
+// Level maps the `uwe.EventLevel` to the `slog.Level`.
+// Fatal events are logged as errors, because the `Chief` handles them itself.
+func Level(level uwe.EventLevel) slog.Level {
+	switch level {
+	case uwe.LvlFatal, uwe.LvlError:
+		return slog.LevelError
+	case uwe.LvlInfo:
+		return slog.LevelInfo
+	case uwe.LvlDebug:
+		return slog.LevelDebug
+	default:
+		return slog.LevelWarn
+	}
+}
@@ -0,0 +1,83 @@
+package sloghook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+)
+
+func TestChiefHandler(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	handler := ChiefHandler(slog.New(slog.NewJSONHandler(buf, nil)))
+
+	emitted := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	handler(uwe.Event{
+		Level:   uwe.LvlError,
+		Kind:    uwe.KindWorkerFailed,
+		Worker:  "api",
+		Message: "Worker failed",
+		Fields:  map[string]interface{}{"error": "stale", "attempt": 2},
+		Err:     fmt.Errorf("run: %w", io.EOF),
+		Time:    emitted,
+		Seq:     7,
+	})
+	handler(uwe.Event{Level: uwe.LvlDebug, Kind: uwe.KindGeneric, Message: "skipped"})
+	handler(uwe.Event{Level: uwe.LvlWarn, Kind: uwe.KindWorkerDegraded, Worker: "db", Message: "Worker is degraded"})
+
+	var records []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]interface{}
+		if err := dec.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records(%d) != 2, debug event must be filtered by the handler level", len(records))
+	}
+
+	expected := map[string]interface{}{
+		"level":   "ERROR",
+		"msg":     "Worker failed",
+		"worker":  "api",
+		"kind":    "worker_failed",
+		"seq":     float64(7),
+		"attempt": float64(2),
+		"error":   "run: EOF",
+		"time":    emitted.Format(time.RFC3339),
+	}
+	for k, v := range expected {
+		if records[0][k] != v {
+			t.Errorf("%s: %v != %v", k, records[0][k], v)
+		}
+	}
+
+	if records[1]["level"] != "WARN" || records[1]["worker"] != "db" || records[1]["kind"] != "worker_degraded" {
+		t.Errorf("unexpected record: %v", records[1])
+	}
+	if _, ok := records[1]["seq"]; ok {
+		t.Errorf("zero seq must be omitted: %v", records[1])
+	}
+}
+
+func TestLevel(t *testing.T) {
+	for level, expected := range map[uwe.EventLevel]slog.Level{
+		uwe.LvlFatal: slog.LevelError,
+		uwe.LvlError: slog.LevelError,
+		uwe.LvlWarn:  slog.LevelWarn,
+		uwe.LvlInfo:  slog.LevelInfo,
+		uwe.LvlDebug: slog.LevelDebug,
+		"unknown":    slog.LevelWarn,
+	} {
+		if Level(level) != expected {
+			t.Errorf("%s: %s != %s", level, Level(level), expected)
+		}
+	}
+}
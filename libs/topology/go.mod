@@ -0,0 +1,13 @@
+module github.com/lancer-kit/uwe/libs/topology
+
+go 1.17
+
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/lancer-kit/uwe/v3 v3.0.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/sheb-gregor/sam v1.0.0 // indirect
+
+replace github.com/lancer-kit/uwe/v3 => ../../
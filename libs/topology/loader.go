@@ -0,0 +1,106 @@
+// Package topology loads the `uwe.TopologyConfig` from the JSON, YAML and TOML files.
+package topology
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/lancer-kit/uwe/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a format of the topology config.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// FormatOf returns the format of the config file by its extension.
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("%s: unsupported config format", path)
+	}
+}
+
+// Decode reads the `uwe.TopologyConfig` in the passed format.
+// Unknown fields are rejected to catch the typos in the config.
+func Decode(r io.Reader, format Format) (uwe.TopologyConfig, error) {
+	var cfg uwe.TopologyConfig
+
+	switch format {
+	case FormatJSON:
+		return uwe.DecodeTopologyJSON(r)
+
+	case FormatYAML:
+		dec := yaml.NewDecoder(r)
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && err != io.EOF {
+			return cfg, fmt.Errorf("%w: %s", uwe.ErrInvalidTopology, err)
+		}
+
+	case FormatTOML:
+		md, err := toml.NewDecoder(r).Decode(&cfg)
+		if err != nil {
+			return cfg, fmt.Errorf("%w: %s", uwe.ErrInvalidTopology, err)
+		}
+		var unknown []string
+		for _, key := range md.Undecoded() {
+			// nested tables of the params are decoded as maps, but reported as undecoded
+			if len(key) > 2 && key[0] == "workers" && key[1] == "params" {
+				continue
+			}
+			unknown = append(unknown, key.String())
+		}
+		if len(unknown) > 0 {
+			return cfg, fmt.Errorf("%w: unknown fields: %s", uwe.ErrInvalidTopology, strings.Join(unknown, ", "))
+		}
+
+	default:
+		return cfg, fmt.Errorf("unsupported config format(%s)", format)
+	}
+
+	return cfg, nil
+}
+
+// LoadFile reads the `uwe.TopologyConfig` from the file, the format is detected by the file extension.
+func LoadFile(path string) (uwe.TopologyConfig, error) {
+	format, err := FormatOf(path)
+	if err != nil {
+		return uwe.TopologyConfig{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return uwe.TopologyConfig{}, err
+	}
+
+	cfg, err := Decode(bytes.NewReader(data), format)
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Build loads the config file and builds the `uwe.Chief` with the workers from the `registry`.
+func Build(registry *uwe.Registry, path string) (uwe.Chief, error) {
+	cfg, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return registry.Build(cfg)
+}
@@ -0,0 +1,107 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+)
+
+type apiParams struct {
+	Addr   string `json:"addr"`
+	Limits struct {
+		RPS int `json:"rps"`
+	} `json:"limits"`
+}
+
+func TestLoadFile(t *testing.T) {
+	var configs []uwe.TopologyConfig
+	for _, path := range []string{"testdata/topology.json", "testdata/topology.yaml", "testdata/topology.toml"} {
+		cfg, err := LoadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var params apiParams
+		if err := cfg.Workers[1].Params.Decode(&params); err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		if params.Addr != ":8080" || params.Limits.RPS != 100 {
+			t.Errorf("%s: unexpected params: %+v", path, params)
+		}
+
+		// numeric params have different types in each format
+		cfg.Workers[1].Params = nil
+		configs = append(configs, cfg)
+	}
+
+	cfg := configs[0]
+	if time.Duration(cfg.ForceStopTimeout) != 5*time.Second ||
+		time.Duration(cfg.Workers[1].Backoff.InitialDelay) != 10*time.Millisecond ||
+		cfg.Workers[1].HealthCheck.RestartAfter != 3 ||
+		cfg.Workers[2].IsEnabled() {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	for i, other := range configs[1:] {
+		if !reflect.DeepEqual(cfg, other) {
+			t.Errorf("config %d differs from json:\n%+v\n%+v", i+1, other, cfg)
+		}
+	}
+}
+
+func TestDecode_UnknownFields(t *testing.T) {
+	for format, data := range map[Format]string{
+		FormatJSON: `{"workers": [{"name": "api", "restart_mode": "no"}]}`,
+		FormatYAML: "workers:\n  - name: api\n    restart_mode: no\n",
+		FormatTOML: "[[workers]]\nname = \"api\"\nrestart_mode = \"no\"\n",
+	} {
+		_, err := Decode(strings.NewReader(data), format)
+		if !errors.Is(err, uwe.ErrInvalidTopology) || !strings.Contains(err.Error(), "restart_mode") {
+			t.Errorf("%s: unexpected error: %v", format, err)
+		}
+	}
+
+	if _, err := LoadFile("topology.ini"); err == nil {
+		t.Error("unsupported format was accepted")
+	}
+}
+
+type noopWorker struct{}
+
+func (noopWorker) Run(ctx uwe.Context) error {
+	uwe.SignalReady(ctx)
+	<-ctx.Done()
+	return nil
+}
+
+func TestBuild(t *testing.T) {
+	registry := uwe.NewRegistry()
+	_ = registry.Register("noop", func(uwe.WorkerName, uwe.WorkerParams) (uwe.Worker, error) {
+		return noopWorker{}, nil
+	})
+
+	chief, err := Build(registry, "testdata/topology.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chief.SetEventHandler(func(uwe.Event) {})
+	chief.SetLocker(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := chief.WaitReady(ctx, "db", "api"); err != nil {
+			t.Error(err)
+		}
+	})
+	chief.Run()
+
+	states := chief.GetWorkersStates()
+	if len(states) != 2 || states["db"] != uwe.WStateStopped || states["api"] != uwe.WStateStopped {
+		t.Errorf("unexpected workers: %v", states)
+	}
+}
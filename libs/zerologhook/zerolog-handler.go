@@ -5,7 +5,7 @@ import (
 	"github.com/rs/zerolog"
 )
 
-// ChiefHandler returns default `EventHandler` that can be used for `Chief.SetEventHandler(...)`.
+// ChiefHandler returns default `EventHandler` that can be used for `Chief.AddEventHandler(...)`.
 func ChiefHandler(log zerolog.Logger) func(event uwe.Event) {
 	return func(event uwe.Event) {
 		var level zerolog.Level
@@ -14,20 +14,31 @@ func ChiefHandler(log zerolog.Logger) func(event uwe.Event) {
 			level = zerolog.ErrorLevel
 		case uwe.LvlInfo:
 			level = zerolog.InfoLevel
+		case uwe.LvlDebug:
+			level = zerolog.DebugLevel
 		default:
 			level = zerolog.WarnLevel
 		}
 		l := log.WithLevel(level)
 
-		if event.Fields == nil {
-			event.Fields = map[string]interface{}{}
-		}
-		event.Fields["worker"] = event.Worker
-
 		for s, i := range event.Fields {
+			// the preserved error is added below, so the key is not duplicated
+			if s == "error" && event.Err != nil {
+				continue
+			}
 			l = l.Interface(s, i)
 		}
 
+		l = l.Str("worker", string(event.Worker)).
+			Str("kind", string(event.Kind)).
+			Uint64("seq", event.Seq)
+		if !event.Time.IsZero() {
+			l = l.Time("event_time", event.Time)
+		}
+		if event.Err != nil {
+			l = l.AnErr("error", event.Err)
+		}
+
 		l.Msg(event.Message)
 	}
 }
@@ -0,0 +1,36 @@
+package zerologhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lancer-kit/uwe/v3"
+	"github.com/rs/zerolog"
+)
+
+func TestChiefHandler(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	handler := ChiefHandler(zerolog.New(buf))
+
+	handler(uwe.Event{
+		Level:   uwe.LvlError,
+		Kind:    uwe.KindWorkerFailed,
+		Worker:  "api",
+		Message: "Worker failed",
+		Fields:  map[string]interface{}{"error": "stale", "attempt": 2},
+		Err:     fmt.Errorf("run: %w", io.EOF),
+	})
+
+	record := buf.String()
+	if n := strings.Count(record, `"error":`); n != 1 {
+		t.Errorf("error keys(%d) != 1: %s", n, record)
+	}
+	for _, field := range []string{`"error":"run: EOF"`, `"attempt":2`, `"worker":"api"`, `"kind":"worker_failed"`} {
+		if !strings.Contains(record, field) {
+			t.Errorf("record does not contain %s: %s", field, record)
+		}
+	}
+}
@@ -3,7 +3,8 @@
 //
 // `Chief` is a supervisor that can be placed at the top of the go application's execution stack,
 // it is blocked until SIGTERM is intercepted and then it shutdown all workers gracefully.
-// Also, `Chief` can be used as a child supervisor inside the` Worker`, which is launched by `Chief` at the top-level.
+// Also, `Chief` can be used as a child supervisor inside the` Worker`, which is launched by `Chief` at the top-level,
+// use `NewSupervisorWorker` to wrap the child `Chief` into the `Worker`.
 //
 // `Worker` is an interface for async workers which launches and manages by the **Chief**.
 //
@@ -18,11 +19,21 @@
 // Workers lifecycle:
 //
 // ```text
-// (*) -> [New] -> [Initialized] -> [Run] -> [Stopped]
+// (*) -> [New] -> [Initialized] -> [Run] -> [Ready] -> [Stopped]
 //
-//	|             |           |
-//	|             |           ↓
-//	|-------------|------> [Failed]
+//	|             |           |         |
+//	|             |           ↓         ↓
+//	|-------------|------> [Failed] <----
+//
+// The worker moves from [Run] to [Ready] when it calls `SignalReady(ctx)`.
+// The worker that implements `WorkerWithHealthCheck` moves from [Run] or [Ready] to [Degraded]
+// when its health check fails and back when the check passes again.
 //
 // ```
+//
+// The optional features of the workers and the IMQ are the extension interfaces, which are checked
+// by the package functions with the same names: the `SignalReady` and `Heartbeat` of the `Context`,
+// the `SendMessage`, `TrySend`, `Request`, `Publish`, `Subscribe` and `Unsubscribe` of the buses.
+// The `Context` passed by the `Chief` and the buses of the `Broker` implement all of them,
+// custom `IMQBroker` implementations can support only a part of them.
 package uwe
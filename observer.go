@@ -0,0 +1,106 @@
+package uwe
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sheb-gregor/sam"
+)
+
+// Observer receives notifications about the workers lifecycle and the IMQ traffic.
+// Unlike the `Event`, the notifications are structured, so they are suitable for the metrics collection.
+// Methods are called synchronously from the `Chief` internals,
+// so implementation must be safe for concurrent use and must not block.
+type Observer interface {
+	// WorkerStateChanged is called on each transition of the worker state.
+	WorkerStateChanged(name WorkerName, from, to sam.State)
+	// WorkerInitialized is called after the `Init` of the worker.
+	WorkerInitialized(name WorkerName, duration time.Duration, err error)
+	// WorkerRunFinished is called after each `Run` of the worker,
+	// `panicked` is true if the run was finished with the panic.
+	WorkerRunFinished(name WorkerName, duration time.Duration, err error, panicked bool)
+	// WorkerRestarted is called before the restart of the failed worker.
+	WorkerRestarted(name WorkerName)
+	// MessageSent is called when the broker accepts the message for the delivery.
+	MessageSent(from, to WorkerName)
+	// MessageDelivered is called when the message is put into the worker mailbox.
+	MessageDelivered(from, to WorkerName)
+	// MessageDropped is called when the message can not be delivered,
+	// for example, if the target worker does not exist.
+	MessageDropped(from, to WorkerName)
+}
+
+// ObservableBroker is an `IMQBroker` that can report its traffic to the `Observer`.
+// The `Chief` passes its observers to the broker that implements this interface.
+type ObservableBroker interface {
+	IMQBroker
+	SetObserver(Observer)
+}
+
+// MailboxStats is an `IMQBroker` that can report the number of
+// messages waiting for the delivery to each worker.
+type MailboxStats interface {
+	MailboxDepths() map[WorkerName]int
+}
+
+// NopObserver is an empty `Observer`, it can be embedded
+// into the implementation that handles only part of the notifications.
+type NopObserver struct{}
+
+func (NopObserver) WorkerStateChanged(WorkerName, sam.State, sam.State)      {}
+func (NopObserver) WorkerInitialized(WorkerName, time.Duration, error)       {}
+func (NopObserver) WorkerRunFinished(WorkerName, time.Duration, error, bool) {}
+func (NopObserver) WorkerRestarted(WorkerName)                               {}
+func (NopObserver) MessageSent(WorkerName, WorkerName)                       {}
+func (NopObserver) MessageDelivered(WorkerName, WorkerName)                  {}
+func (NopObserver) MessageDropped(WorkerName, WorkerName)                    {}
+
+// observerSet broadcasts the notifications to all registered observers.
+type observerSet struct {
+	mutex sync.RWMutex
+	list  []Observer
+}
+
+func (s *observerSet) add(o Observer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.list = append(s.list, o)
+}
+
+func (s *observerSet) each(fn func(o Observer)) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, o := range s.list {
+		fn(o)
+	}
+}
+
+func (s *observerSet) WorkerStateChanged(name WorkerName, from, to sam.State) {
+	s.each(func(o Observer) { o.WorkerStateChanged(name, from, to) })
+}
+
+func (s *observerSet) WorkerInitialized(name WorkerName, duration time.Duration, err error) {
+	s.each(func(o Observer) { o.WorkerInitialized(name, duration, err) })
+}
+
+func (s *observerSet) WorkerRunFinished(name WorkerName, duration time.Duration, err error, panicked bool) {
+	s.each(func(o Observer) { o.WorkerRunFinished(name, duration, err, panicked) })
+}
+
+func (s *observerSet) WorkerRestarted(name WorkerName) {
+	s.each(func(o Observer) { o.WorkerRestarted(name) })
+}
+
+func (s *observerSet) MessageSent(from, to WorkerName) {
+	s.each(func(o Observer) { o.MessageSent(from, to) })
+}
+
+func (s *observerSet) MessageDelivered(from, to WorkerName) {
+	s.each(func(o Observer) { o.MessageDelivered(from, to) })
+}
+
+func (s *observerSet) MessageDropped(from, to WorkerName) {
+	s.each(func(o Observer) { o.MessageDropped(from, to) })
+}
@@ -1,10 +1,13 @@
 package uwe
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/sheb-gregor/sam"
 )
@@ -13,6 +16,44 @@ import (
 type workerPool struct {
 	mutex   sync.RWMutex
 	workers map[WorkerName]*workerRO
+	groups  map[GroupName]*workerGroup
+	// changed is closed and replaced each time when the state of any worker changes.
+	changed chan struct{}
+
+	// escalate is called when the worker failure must stop the whole `Chief`.
+	escalate func(reason StopReason, name WorkerName, err error)
+	observer *observerSet
+}
+
+func newWorkerPool() *workerPool {
+	return &workerPool{
+		workers:  make(map[WorkerName]*workerRO),
+		changed:  make(chan struct{}),
+		observer: &observerSet{},
+	}
+}
+
+// workerGroup is a set of workers supervised together.
+type workerGroup struct {
+	strategy SupervisionStrategy
+	members  []WorkerName
+}
+
+// setGroup defines the supervision strategy of the group.
+func (p *workerPool) setGroup(name GroupName, strategy SupervisionStrategy) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.groups == nil {
+		p.groups = map[GroupName]*workerGroup{}
+	}
+
+	group, ok := p.groups[name]
+	if !ok {
+		group = &workerGroup{}
+		p.groups[name] = group
+	}
+	group.strategy = strategy
 }
 
 // setWorker adds worker into pool.
@@ -27,8 +68,10 @@ func (p *workerPool) setWorker(name WorkerName, worker Worker, opts []WorkerOpts
 
 	p.workers[name] = &workerRO{
 		StateMachine: sm,
+		name:         name,
 		worker:       worker,
 		canceler:     nil,
+		observer:     p.observer,
 	}
 
 	for _, opt := range opts {
@@ -36,9 +79,43 @@ func (p *workerPool) setWorker(name WorkerName, worker Worker, opts []WorkerOpts
 		switch o := opt.(type) {
 		case RestartOption:
 			p.workers[name].restartMode |= o
+		case BackoffOption:
+			p.workers[name].backoff = newBackoff(o)
+		case RestartLimit:
+			limit := o
+			p.workers[name].restartLimit = &limit
+		case GroupName:
+			p.workers[name].group = o
+		case Dependencies:
+			p.workers[name].dependsOn = append(p.workers[name].dependsOn, o...)
+		case ReadyDependencies:
+			p.workers[name].dependsOn = append(p.workers[name].dependsOn, o...)
+			p.workers[name].readyDeps = append(p.workers[name].readyDeps, o...)
+		case StopTimeout:
+			p.workers[name].stopTimeout = time.Duration(o)
+		case HealthCheckOption:
+			check := o
+			p.workers[name].healthCheck = &check
+		case Watchdog:
+			p.workers[name].watchdog = time.Duration(o)
+		case ReloadOption:
+			p.workers[name].reloadMode = o
+		case MailboxOption:
+			mailbox := o
+			p.workers[name].mailbox = &mailbox
 		}
 	}
 
+	if group := p.workers[name].group; group != "" {
+		if p.groups == nil {
+			p.groups = map[GroupName]*workerGroup{}
+		}
+		if _, ok := p.groups[group]; !ok {
+			p.groups[group] = &workerGroup{strategy: OneForOne}
+		}
+		p.groups[group].members = append(p.groups[group].members, name)
+	}
+
 	if p.workers[name].restartMode == 0 {
 		p.workers[name].restartMode = NoRestart
 	}
@@ -46,19 +123,491 @@ func (p *workerPool) setWorker(name WorkerName, worker Worker, opts []WorkerOpts
 	return nil
 }
 
+// workersList returns names of all workers ordered by dependencies:
+// each worker is placed after the workers it depends on.
 func (p *workerPool) workersList() []WorkerName {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
 	list := make([]WorkerName, 0, len(p.workers))
 	for name := range p.workers {
 		list = append(list, name)
 	}
 
+	levels := p.dependencyLevels()
+	sort.Slice(list, func(i, j int) bool {
+		if levels[list[i]] != levels[list[j]] {
+			return levels[list[i]] < levels[list[j]]
+		}
+		return list[i] < list[j]
+	})
+
+	return list
+}
+
+// shutdownOrder returns names of all workers grouped by the dependency level
+// in the reverse order: dependents go before the workers they depend on.
+func (p *workerPool) shutdownOrder() [][]WorkerName {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	levels := p.dependencyLevels()
+	var maxLevel int
+	for _, level := range levels {
+		if level > maxLevel {
+			maxLevel = level
+		}
+	}
+
+	order := make([][]WorkerName, maxLevel+1)
+	for name, level := range levels {
+		order[maxLevel-level] = append(order[maxLevel-level], name)
+	}
+
+	return order
+}
+
+// dependencyLevels returns the depth of each worker in the dependency graph.
+// Workers without dependencies have level 0. Dependencies must be validated before.
+func (p *workerPool) dependencyLevels() map[WorkerName]int {
+	levels := make(map[WorkerName]int, len(p.workers))
+
+	var levelOf func(name WorkerName) int
+	levelOf = func(name WorkerName) int {
+		if level, ok := levels[name]; ok {
+			return level
+		}
+
+		var level int
+		if w, ok := p.workers[name]; ok {
+			for _, dep := range w.dependsOn {
+				if l := levelOf(dep) + 1; l > level {
+					level = l
+				}
+			}
+		}
+
+		levels[name] = level
+		return level
+	}
+
+	for name := range p.workers {
+		levelOf(name)
+	}
+
+	return levels
+}
+
+// validateDependencies checks that all worker dependencies
+// are registered in the pool and there are no dependency cycles.
+func (p *workerPool) validateDependencies() error {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	deps := make(map[WorkerName][]WorkerName, len(p.workers))
+	for name, w := range p.workers {
+		deps[name] = w.dependsOn
+	}
+
+	return checkDependencies(deps)
+}
+
+// checkDependencies checks that all dependencies are present
+// in the `deps` map and there are no dependency cycles.
+func checkDependencies(deps map[WorkerName][]WorkerName) error {
+	const (
+		unvisited = iota
+		inProgress
+		visited
+	)
+
+	marks := make(map[WorkerName]int, len(deps))
+	var path []WorkerName
+
+	var visit func(name WorkerName) error
+	visit = func(name WorkerName) error {
+		switch marks[name] {
+		case visited:
+			return nil
+		case inProgress:
+			cycle := []string{string(name)}
+			for i := len(path) - 1; i >= 0 && path[i] != name; i-- {
+				cycle = append([]string{string(path[i])}, cycle...)
+			}
+			cycle = append([]string{string(name)}, cycle...)
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+		}
+
+		marks[name] = inProgress
+		path = append(path, name)
+
+		for _, dep := range deps[name] {
+			if _, ok := deps[dep]; !ok {
+				return fmt.Errorf("%s: depends on unknown worker(%s)", name, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		marks[name] = visited
+		return nil
+	}
+
+	names := make([]WorkerName, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForDependencies blocks until all dependencies of the worker
+// reach the `WStateRun` or `WStateReady` state, and the `ReadyDependencies` reach the `WStateReady` state.
+// It returns `false` if the stop was initiated before dependencies started.
+func (p *workerPool) waitForDependencies(ctx Context, emit func(Event), name WorkerName) bool {
+	w := p.getWorker(name)
+	deps, readyDeps := w.dependsOn, w.readyDeps
+	if len(deps) == 0 {
+		return true
+	}
+
+	var notified bool
+	notify := func(pending []WorkerName) {
+		if notified {
+			return
+		}
+
+		notified = true
+		emit(Event{
+			Level: LvlInfo, Kind: KindWorkerWaitingDependencies, Worker: name,
+			Message: "Worker is waiting for dependencies",
+			Fields:  map[string]interface{}{"dependencies": pending},
+		})
+	}
+
+	err := p.waitForStates(ctx, readyDeps, func(state sam.State) bool { return state == WStateReady }, notify)
+	if err == nil {
+		err = p.waitForStates(ctx, deps, IsRunning, notify)
+	}
+
+	return err == nil
+}
+
+// waitForStates blocks until the state of each worker satisfies the condition.
+// The `onPending` callback, if set, is called with the list of unsatisfied workers on each check.
+func (p *workerPool) waitForStates(ctx context.Context, names []WorkerName,
+	cond func(sam.State) bool, onPending func([]WorkerName)) error {
+	for {
+		var pending []WorkerName
+		p.mutex.RLock()
+		for _, name := range names {
+			w, ok := p.workers[name]
+			if !ok || !cond(w.State()) {
+				pending = append(pending, name)
+			}
+		}
+		changed := p.changed
+		p.mutex.RUnlock()
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		if onPending != nil {
+			onPending(pending)
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// setRuntime sets the cancel function of the worker context
+// and the channel which is closed when the worker goroutine finishes.
+func (p *workerPool) setRuntime(name WorkerName, cancel context.CancelFunc, done chan struct{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.workers[name].canceler = cancel
+	p.workers[name].done = done
+}
+
+// stuckWorker describes the worker that did not stop in time.
+type stuckWorker struct {
+	name    WorkerName
+	timeout time.Duration
+	elapsed time.Duration
+	stack   string
+}
+
+// stopWorkers cancels the contexts of the workers and waits until they finish,
+// but no longer than the worker `StopTimeout` or the `defaultTimeout`, if it is not set,
+// and no longer than the `deadline`, if it is not zero. It returns the workers that did not stop in time.
+func (p *workerPool) stopWorkers(names []WorkerName, defaultTimeout time.Duration, deadline time.Time) []stuckWorker {
+	type stopping struct {
+		name    WorkerName
+		done    chan struct{}
+		timeout time.Duration
+	}
+
+	var list []stopping
+	p.mutex.RLock()
+	for _, name := range names {
+		w, ok := p.workers[name]
+		if !ok || w.canceler == nil {
+			continue
+		}
+
+		timeout := defaultTimeout
+		if w.stopTimeout > 0 {
+			timeout = w.stopTimeout
+		}
+		if left := time.Until(deadline); !deadline.IsZero() && left < timeout {
+			timeout = 0
+			if left > 0 {
+				timeout = left
+			}
+		}
+
+		w.canceler()
+		list = append(list, stopping{name: name, done: w.done, timeout: timeout})
+	}
+	p.mutex.RUnlock()
+
+	startedAt := time.Now()
+	results := make(chan *stuckWorker, len(list))
+	for _, w := range list {
+		go func(w stopping) {
+			timer := time.NewTimer(w.timeout)
+			defer timer.Stop()
+
+			select {
+			case <-w.done:
+				results <- nil
+			case <-timer.C:
+				results <- &stuckWorker{
+					name:    w.name,
+					timeout: w.timeout,
+					elapsed: time.Since(startedAt),
+					stack:   goroutineStack(p.goroutineOf(w.name)),
+				}
+			}
+		}(w)
+	}
+
+	var stuck []stuckWorker
+	for range list {
+		if r := <-results; r != nil {
+			stuck = append(stuck, *r)
+		}
+	}
+
+	return stuck
+}
+
+// setGoroutine saves the id of the goroutine in which the worker is executed.
+func (p *workerPool) setGoroutine(name WorkerName, id uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if w, ok := p.workers[name]; ok {
+		w.goroutine = id
+	}
+}
+
+// goroutineOf returns the id of the goroutine in which the worker is executed.
+func (p *workerPool) goroutineOf(name WorkerName) uint64 {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if w, ok := p.workers[name]; ok {
+		return w.goroutine
+	}
+	return 0
+}
+
+// stopTimeout returns the `StopTimeout` of the worker or the `defaultTimeout`, if it is not set.
+func (p *workerPool) stopTimeout(name WorkerName, defaultTimeout time.Duration) time.Duration {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if w, ok := p.workers[name]; ok && w.stopTimeout > 0 {
+		return w.stopTimeout
+	}
+	return defaultTimeout
+}
+
+// isLaunched reports whether the worker goroutine is launched and not finished yet.
+func (p *workerPool) isLaunched(name WorkerName) (bool, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	w, ok := p.workers[name]
+	if !ok || w.removing {
+		return false, fmt.Errorf("%s: %w", name, ErrWorkerNotExist)
+	}
+	return w.launched(), nil
+}
+
+// beginRemove marks the worker as removing, so it can not be started or restarted,
+// and reports whether it is launched.
+func (p *workerPool) beginRemove(name WorkerName) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok || w.removing {
+		return false, fmt.Errorf("%s: %w", name, ErrWorkerNotExist)
+	}
+
+	w.removing = true
+	return w.launched(), nil
+}
+
+// cancelRemove clears the mark set by the `beginRemove`, if the worker was not removed.
+func (p *workerPool) cancelRemove(name WorkerName) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if w, ok := p.workers[name]; ok {
+		w.removing = false
+	}
+}
+
+// resetWorker moves the stopped worker back to the `WStateNew` state to launch it again.
+func (p *workerPool) resetWorker(name WorkerName) error {
+	sm, err := newWorkerSM()
+	if err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok || w.removing {
+		return fmt.Errorf("%s: %w", name, ErrWorkerNotExist)
+	}
+
+	w.StateMachine = sm
+	w.run = nil
+	w.health = healthStatus{}
+	return nil
+}
+
+// resetWorkers moves all workers, that were run before, back to the `WStateNew` state.
+func (p *workerPool) resetWorkers() error {
+	for _, name := range p.workersList() {
+		w := p.getWorker(name)
+
+		p.mutex.RLock()
+		state := w.State()
+		p.mutex.RUnlock()
+
+		if state == WStateNew {
+			continue
+		}
+		if err := p.resetWorker(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getChildSupervisors returns workers that are the child supervisors.
+func (p *workerPool) getChildSupervisors() map[WorkerName]stateTreeProvider {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	r := map[WorkerName]stateTreeProvider{}
+	for name, w := range p.workers {
+		if child, ok := w.worker.(stateTreeProvider); ok {
+			r[name] = child
+		}
+	}
+	return r
+}
+
+// interruptRun cancels the current run of the worker to restart it.
+func (p *workerPool) interruptRun(name WorkerName, reInit bool, reason string) (*workerRun, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok || w.removing {
+		return nil, fmt.Errorf("%s: %w", name, ErrWorkerNotExist)
+	}
+
+	if w.run == nil || !w.run.interrupt(reInit, reason) {
+		return nil, fmt.Errorf("%s: %w", name, ErrWorkerNotRunning)
+	}
+
+	return w.run, nil
+}
+
+// dependents returns the workers that depend on the worker.
+func (p *workerPool) dependents(name WorkerName) []WorkerName {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var list []WorkerName
+	for wName, w := range p.workers {
+		for _, dep := range w.dependsOn {
+			if dep == name {
+				list = append(list, wName)
+				break
+			}
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
 	return list
 }
 
+// removeWorker deletes the worker from the pool and from its group.
+func (p *workerPool) removeWorker(name WorkerName) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok {
+		return
+	}
+
+	if group, ok := p.groups[w.group]; ok {
+		for i, member := range group.members {
+			if member == name {
+				group.members = append(group.members[:i:i], group.members[i+1:]...)
+				break
+			}
+		}
+	}
+
+	delete(p.workers, name)
+	p.notifyChanged()
+}
+
 // runWorkerExec adds worker into pool.
-func (p *workerPool) runWorkerExec(ctx Context, eventChan chan<- Event, name WorkerName) error {
+func (p *workerPool) runWorkerExec(ctx Context, emit func(Event), name WorkerName) error {
 	w := p.getWorker(name)
 
+	if !p.waitForDependencies(ctx, emit, name) {
+		return nil
+	}
+
 InitPoint:
 	if err := p.setState(name, WStateInitialized); err != nil {
 		return err
@@ -66,37 +615,41 @@ InitPoint:
 
 	worker, ok := w.worker.(WorkerWithInit)
 	if ok {
-		if err := worker.Init(); err != nil {
-			eventChan <- Event{
-				Level: LvlFatal, Worker: name,
+		initStartedAt := time.Now()
+		err := worker.Init()
+		p.observer.WorkerInitialized(name, time.Since(initStartedAt), err)
+		if err != nil {
+			p.recordError(name, err, "")
+			emit(Event{
+				Level: LvlFatal, Kind: KindWorkerInitFailed, Worker: name,
 				Message: "Worker can not be initialized due to an error",
 				Fields:  map[string]interface{}{"error": err.Error()},
-			}
+				Err:     err,
+			})
 
 			if w.restartMode == StopAppOnFail {
-				msg := fmt.Sprintf("execution cannot be continued due to a failed worker(%s)", name)
-				eventChan <- Event{Level: LvlFatal, Worker: name, Message: msg}
-				panic(msg) // TODO: tbd, probably it is better to replace panic by os.Exit(1)
+				p.escalate(StopByInitFailure, name,
+					fmt.Errorf("execution cannot be continued due to a failed worker(%s): %w", name, err))
 			}
 
 			return err
 		}
-		eventChan <- Event{
-			Level: LvlInfo, Worker: name,
+		emit(Event{
+			Level: LvlInfo, Kind: KindWorkerInitialized, Worker: name,
 			Message: "Worker is initialized",
-		}
+		})
 	}
 
 RunPoint:
 	if err := p.startWorker(name); err != nil {
 		return err
 	}
-	eventChan <- Event{
-		Level: LvlInfo, Worker: name,
+	emit(Event{
+		Level: LvlInfo, Kind: KindWorkerStarting, Worker: name,
 		Message: "Starting worker",
-	}
+	})
 
-	var runClosure = func() (panicked bool, e error) {
+	var runClosure = func(runCtx Context) (panicked bool, e error) {
 		defer func() {
 			r := recover()
 			if r == nil {
@@ -111,38 +664,70 @@ RunPoint:
 				e = fmt.Errorf("%v", r)
 			}
 
-			eventChan <- Event{
-				Level: LvlError, Worker: name,
+			stack := string(debug.Stack())
+			p.recordError(name, e, stack)
+			emit(Event{
+				Level: LvlError, Kind: KindWorkerPanicked, Worker: name,
 				Message: "Worker failed with panic",
 				Fields: map[string]interface{}{
 					"error": e.Error(),
-					"stack": string(debug.Stack()),
+					"stack": stack,
 				},
-			}
+				Err: e,
+			})
 		}()
 
-		e = w.worker.Run(ctx)
+		e = w.worker.Run(runCtx)
 		if e != nil {
-			eventChan <- Event{
-				Level: LvlError, Worker: name,
+			p.recordError(name, e, "")
+			emit(Event{
+				Level: LvlError, Kind: KindWorkerFailed, Worker: name,
 				Message: "Worker ended execution with error",
 				Fields:  map[string]interface{}{"error": e.Error()},
-			}
+				Err:     e,
+			})
 		}
 		return
 	}
 
-	eventChan <- Event{
-		Level: LvlInfo, Worker: name,
+	emit(Event{
+		Level: LvlInfo, Kind: KindWorkerStarted, Worker: name,
 		Message: "Run worker",
+	})
+
+	run := p.beginRun(ctx, name)
+	p.watchHealth(emit, name, run)
+	p.watchHeartbeats(emit, name, run)
+	runStartedAt := time.Now()
+	panicked, err := runClosure(run.ctx)
+	p.observer.WorkerRunFinished(name, time.Since(runStartedAt), err, panicked)
+	if p.endRun(name, run) && !stopInitiated(ctx) {
+		if e := p.failWorker(name); e != nil {
+			return e
+		}
+
+		if run.reInit {
+			emit(Event{
+				Level: LvlInfo, Kind: KindWorkerRestarting, Worker: name,
+				Message: "Worker will be re-initialized and restarted",
+				Fields:  map[string]interface{}{"reason": run.reason},
+			})
+			goto InitPoint
+		}
+
+		emit(Event{
+			Level: LvlInfo, Kind: KindWorkerRestarting, Worker: name,
+			Message: "Worker will be restarted",
+			Fields:  map[string]interface{}{"reason": run.reason},
+		})
+		goto RunPoint
 	}
 
-	panicked, err := runClosure()
 	if !panicked && err == nil {
-		eventChan <- Event{
-			Level: LvlInfo, Worker: name,
+		emit(Event{
+			Level: LvlInfo, Kind: KindWorkerStopped, Worker: name,
 			Message: "Worker ended execution",
-		}
+		})
 		return p.stopWorker(name)
 	}
 
@@ -152,9 +737,9 @@ RunPoint:
 
 	switch {
 	case w.restartMode == StopAppOnFail:
-		msg := fmt.Sprintf("execution cannot be continued due to a failed worker(%s)", name)
-		eventChan <- Event{Level: LvlFatal, Worker: name, Message: msg}
-		panic(msg) // TODO: tbd, probably it is better to replace panic by os.Exit(1)
+		p.escalate(StopByFatalWorker, name,
+			fmt.Errorf("execution cannot be continued due to a failed worker(%s): %w", name, err))
+		return err
 
 	case (panicked && !w.restartMode.Is(RestartOnFail)) ||
 		(!panicked && !w.restartMode.Is(RestartOnError)):
@@ -167,17 +752,40 @@ RunPoint:
 			return err
 		}
 
+		if !p.registerRestart(name) {
+			return p.restartLimitExceeded(emit, name)
+		}
+		p.observer.WorkerRestarted(name)
+
+		p.restartGroup(ctx, emit, name)
+
+		fields := map[string]interface{}{}
+		var delay time.Duration
+		if w.backoff != nil {
+			var attempt int
+			attempt, delay = w.backoff.next(time.Since(runStartedAt))
+			fields["attempt"] = attempt
+			fields["delay"] = delay.String()
+		}
+
 		if w.restartMode.Is(RestartWithReInit) {
-			// todo: log -- init worker again
-			eventChan <- Event{
-				Level: LvlInfo, Worker: name,
+			emit(Event{
+				Level: LvlInfo, Kind: KindWorkerRestarting, Worker: name,
 				Message: "Worker will be re-initialized and restarted",
+				Fields:  fields,
+			})
+			if !waitForRestart(ctx, delay) {
+				return err
 			}
 			goto InitPoint
 		} else {
-			eventChan <- Event{
-				Level: LvlInfo, Worker: name,
+			emit(Event{
+				Level: LvlInfo, Kind: KindWorkerRestarting, Worker: name,
 				Message: "Worker will be restarted",
+				Fields:  fields,
+			})
+			if !waitForRestart(ctx, delay) {
+				return err
 			}
 			goto RunPoint
 		}
@@ -186,7 +794,374 @@ RunPoint:
 	return nil
 }
 
-func stopInitiated(ctx Context) bool {
+// beginRun creates a new handle for the worker run.
+func (p *workerPool) beginRun(ctx Context, name WorkerName) *workerRun {
+	runCtx, cancel := context.WithCancel(ctx)
+	run := &workerRun{
+		ctx:    withParent(ctx, runCtx),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	p.mutex.Lock()
+	if p.workers[name].watchdog > 0 {
+		run.beats = make(chan struct{}, 1)
+	}
+	p.workers[name].run = run
+	p.mutex.Unlock()
+
+	return run
+}
+
+// endRun finalizes the worker run and reports
+// whether it was interrupted to restart the worker.
+func (p *workerPool) endRun(name WorkerName, run *workerRun) bool {
+	run.cancel()
+	run.watchers.Wait()
+	close(run.done)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.workers[name].run = nil
+
+	return run.restart
+}
+
+// watchHealth starts the health checker for the run of the worker,
+// if the worker implements `WorkerWithHealthCheck`. The checker stops with the run.
+func (p *workerPool) watchHealth(emit func(Event), name WorkerName, run *workerRun) {
+	w := p.getWorker(name)
+	worker, ok := w.worker.(WorkerWithHealthCheck)
+	if !ok {
+		return
+	}
+
+	opt := HealthCheckOption{Interval: DefaultHealthCheckInterval}
+	if w.healthCheck != nil {
+		opt = *w.healthCheck
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = DefaultHealthCheckInterval
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = opt.Interval
+	}
+
+	p.mutex.Lock()
+	w.health.failures = 0
+	p.mutex.Unlock()
+
+	run.watchers.Add(1)
+	go func() {
+		defer run.watchers.Done()
+
+		ticker := time.NewTicker(opt.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-run.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			checkCtx, cancel := context.WithTimeout(run.ctx, opt.Timeout)
+			err := worker.HealthCheck(checkCtx)
+			cancel()
+
+			if stopInitiated(run.ctx) {
+				return
+			}
+
+			if err == nil {
+				if p.recoverWorker(name) {
+					emit(Event{
+						Level: LvlInfo, Kind: KindWorkerRecovered, Worker: name,
+						Message: "Worker is healthy again",
+					})
+				}
+				continue
+			}
+
+			degraded, failures := p.degradeWorker(name, err)
+			if degraded {
+				emit(Event{
+					Level: LvlWarn, Kind: KindWorkerDegraded, Worker: name,
+					Message: "Worker is degraded due to a failed health check",
+					Fields:  map[string]interface{}{"error": err.Error()},
+					Err:     err,
+				})
+			}
+
+			if opt.RestartAfter > 0 && failures >= opt.RestartAfter {
+				reason := fmt.Sprintf("%d consecutive failed health checks", failures)
+				reInit := w.restartMode > 0 && w.restartMode.Is(RestartWithReInit)
+				if _, e := p.interruptRun(name, reInit, reason); e == nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// watchHeartbeats starts the watchdog for the run of the worker, if the `Watchdog` is set.
+// When the worker misses the heartbeat deadline, the watchdog reports the stack of the worker goroutine
+// and restarts the worker, if its `RestartOption` allows, otherwise cancels the run.
+func (p *workerPool) watchHeartbeats(emit func(Event), name WorkerName, run *workerRun) {
+	w := p.getWorker(name)
+	if run.beats == nil {
+		return
+	}
+
+	run.watchers.Add(1)
+	go func() {
+		defer run.watchers.Done()
+
+		timer := time.NewTimer(w.watchdog)
+		defer timer.Stop()
+
+		lastBeat := time.Now()
+		for {
+			select {
+			case <-run.ctx.Done():
+				return
+			case <-run.beats:
+				lastBeat = time.Now()
+				// the timer may have fired without the value in the channel,
+				// so the channel is drained without blocking
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(w.watchdog)
+				continue
+			case <-timer.C:
+			}
+
+			emit(Event{
+				Level: LvlError, Kind: KindWorkerHeartbeatMissed, Worker: name,
+				Message: "Worker missed the heartbeat deadline",
+				Fields: map[string]interface{}{
+					"interval":       w.watchdog.String(),
+					"last_heartbeat": time.Since(lastBeat).String(),
+					"stack":          goroutineStack(p.goroutineOf(name)),
+				},
+			})
+
+			reason := fmt.Sprintf("no heartbeat within %s", w.watchdog)
+			switch {
+			case w.restartMode == StopAppOnFail:
+				run.cancel()
+				p.escalate(StopByFatalWorker, name,
+					fmt.Errorf("execution cannot be continued due to a hung worker(%s): %s", name, reason))
+			case w.restartMode > 0 && (w.restartMode.Is(RestartOnFail) || w.restartMode.Is(RestartOnError)):
+				_, _ = p.interruptRun(name, w.restartMode.Is(RestartWithReInit), reason)
+			default:
+				run.cancel()
+			}
+			return
+		}
+	}()
+}
+
+// heartbeat notifies the watchdog of the current worker run that the worker is alive.
+func (p *workerPool) heartbeat(name WorkerName) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	w, ok := p.workers[name]
+	if !ok || w.run == nil || w.run.beats == nil {
+		return
+	}
+
+	select {
+	case w.run.beats <- struct{}{}:
+	default:
+	}
+}
+
+// degradeWorker accounts the failed health check and moves the running worker to the `WStateDegraded` state.
+// It returns `true` if the state was changed and the number of consecutive failures.
+func (p *workerPool) degradeWorker(name WorkerName, err error) (bool, int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok {
+		return false, 0
+	}
+
+	w.health.failures++
+	w.health.lastError = err.Error()
+	w.health.lastCheck = time.Now()
+
+	state := w.State()
+	if state != WStateRun && state != WStateReady {
+		return false, w.health.failures
+	}
+
+	if e := w.goTo(WStateDegraded); e != nil {
+		return false, w.health.failures
+	}
+	w.health.healthyState = state
+	p.notifyChanged()
+
+	return true, w.health.failures
+}
+
+// recoverWorker accounts the successful health check and moves the degraded worker back
+// to the state it had before. It returns `true` if the state was changed.
+func (p *workerPool) recoverWorker(name WorkerName) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok {
+		return false
+	}
+
+	w.health.failures = 0
+	w.health.lastError = ""
+	w.health.lastCheck = time.Now()
+
+	if w.State() != WStateDegraded {
+		return false
+	}
+
+	if e := w.goTo(w.health.healthyState); e != nil {
+		return false
+	}
+	p.notifyChanged()
+
+	return true
+}
+
+// groupPeers returns the group members which must be restarted together with the failed worker.
+func (p *workerPool) groupPeers(name WorkerName) []WorkerName {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	group, ok := p.groups[p.workers[name].group]
+	if !ok {
+		return nil
+	}
+
+	var peers []WorkerName
+	switch group.strategy {
+	case OneForAll:
+		for _, member := range group.members {
+			if member != name {
+				peers = append(peers, member)
+			}
+		}
+	case RestForOne:
+		for i, member := range group.members {
+			if member == name {
+				peers = append(peers, group.members[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return peers
+}
+
+// restartGroup interrupts the running peers of the failed worker
+// according to the group strategy and waits until they stop.
+// Interrupted peers will be re-initialized and restarted.
+func (p *workerPool) restartGroup(ctx Context, emit func(Event), name WorkerName) {
+	peers := p.groupPeers(name)
+	if len(peers) == 0 {
+		return
+	}
+
+	var interrupted []*workerRun
+	p.mutex.Lock()
+	group := p.workers[name].group
+	for _, peer := range peers {
+		run := p.workers[peer].run
+		if run == nil || !run.interrupt(true, fmt.Sprintf("group(%s) restart", group)) {
+			continue
+		}
+
+		interrupted = append(interrupted, run)
+	}
+	strategy := p.groups[group].strategy
+	p.mutex.Unlock()
+
+	emit(Event{
+		Level: LvlInfo, Kind: KindGroupRestarting, Worker: name,
+		Message: "Worker group will be restarted",
+		Fields: map[string]interface{}{
+			"group":    group,
+			"strategy": strategy.String(),
+			"peers":    peers,
+		},
+	})
+
+	for _, run := range interrupted {
+		select {
+		case <-run.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// registerRestart accounts the restart of the worker and checks the `RestartLimit`.
+func (p *workerPool) registerRestart(name WorkerName) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.workers[name].registerRestart(time.Now())
+}
+
+// restartLimitExceeded reports that worker exceeded the `RestartLimit`
+// and escalates the failure if required.
+func (p *workerPool) restartLimitExceeded(emit func(Event), name WorkerName) error {
+	w := p.getWorker(name)
+	err := fmt.Errorf("%w: more than %d restarts within %s",
+		ErrRestartLimitExceeded, w.restartLimit.MaxRestarts, w.restartLimit.Window)
+
+	emit(Event{
+		Level: LvlFatal, Kind: KindWorkerRestartLimit, Worker: name,
+		Message: "Worker exceeded the restart limit and will not be restarted",
+		Fields: map[string]interface{}{
+			"error":        err.Error(),
+			"max_restarts": w.restartLimit.MaxRestarts,
+			"window":       w.restartLimit.Window.String(),
+		},
+		Err: err,
+	})
+
+	if w.restartLimit.Escalate {
+		p.escalate(StopByFatalWorker, name, err)
+	}
+
+	return err
+}
+
+// waitForRestart blocks for the given restart delay.
+// It returns `false` if the stop was initiated before the delay expired.
+func waitForRestart(ctx Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return !stopInitiated(ctx)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func stopInitiated(ctx context.Context) bool {
 	select {
 	case <-ctx.Done():
 		return true
@@ -218,11 +1193,51 @@ func (p *workerPool) getWorkersStates() map[WorkerName]sam.State {
 	return r
 }
 
+// getWorkersInfo returns runtime details of all workers.
+func (p *workerPool) getWorkersInfo() map[WorkerName]WorkerInfo {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	now := time.Now()
+	r := map[WorkerName]WorkerInfo{}
+	for name, worker := range p.workers {
+		r[name] = worker.info(now)
+	}
+	return r
+}
+
 // startWorker sets state `WorkerEnabled` for workers with the specified `name`.
 func (p *workerPool) startWorker(name WorkerName) error {
 	return p.setState(name, WStateRun)
 }
 
+// readyWorker sets state `WStateReady` for the running worker with the specified `name`.
+// The degraded worker becomes ready when it recovers.
+// It returns `false` if the worker is not in the `WStateRun` or `WStateDegraded` state.
+func (p *workerPool) readyWorker(name WorkerName) (bool, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok {
+		return false, nil
+	}
+
+	switch w.State() {
+	case WStateRun:
+		if err := w.goTo(WStateReady); err != nil {
+			return false, fmt.Errorf("%s: %w", string(name), err)
+		}
+		p.notifyChanged()
+		return true, nil
+	case WStateDegraded:
+		w.health.healthyState = WStateReady
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 // stopWorker sets state `WorkerStopped` for workers with the specified `name`.
 func (p *workerPool) stopWorker(name WorkerName) error {
 	return p.setState(name, WStateStopped)
@@ -236,15 +1251,42 @@ func (p *workerPool) failWorker(name WorkerName) error {
 // setState updates state of specified worker.
 func (p *workerPool) setState(name WorkerName, state sam.State) error {
 	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
 	_, ok := p.workers[name]
 	if !ok {
-		return errors.New(string(name) + ": not exist")
+		return fmt.Errorf("%s: %w", name, ErrWorkerNotExist)
 	}
 
-	err := p.workers[name].GoTo(state)
-	p.mutex.Unlock()
-	if err != nil {
+	if err := p.workers[name].goTo(state); err != nil {
 		return fmt.Errorf("%s: %w", string(name), err)
 	}
+
+	p.notifyChanged()
 	return nil
 }
+
+// recordError saves the last error of the worker.
+// The `stack` is set only if the worker panicked.
+func (p *workerPool) recordError(name WorkerName, err error, stack string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	w, ok := p.workers[name]
+	if !ok {
+		return
+	}
+
+	w.lastError = err.Error()
+	if stack != "" {
+		w.panics++
+		w.lastPanicStack = stack
+	}
+}
+
+// notifyChanged wakes up all waiters of the workers state change.
+// It must be called with the locked mutex.
+func (p *workerPool) notifyChanged() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
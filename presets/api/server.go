@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -73,6 +74,7 @@ func NewServerWithInitFunc(config Config, routerInit func(ctx uwe.Context) (http
 func (s *Server) Init() error { return nil }
 
 // Run starts serving the passed `http.Handler` with HTTP server.
+// The worker signals the readiness as soon as the listener is bound.
 func (s *Server) Run(ctx uwe.Context) error {
 	readHeaderTimeout := time.Minute
 	if s.config.ReadHeaderTimeout > 0 {
@@ -94,9 +96,15 @@ func (s *Server) Run(ctx uwe.Context) error {
 		ReadHeaderTimeout: readHeaderTimeout,
 	}
 
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("server failed: %s", err)
+	}
+	uwe.SignalReady(ctx)
+
 	serverFailed := make(chan error)
 	go func() {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			serverFailed <- err
 			close(serverFailed)
 		}
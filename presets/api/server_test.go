@@ -1,9 +1,13 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"testing"
+	"time"
 
 	"github.com/lancer-kit/uwe/v3"
 )
@@ -39,3 +43,43 @@ func getRouter() http.Handler {
 	log.Println("REST API router initialized")
 	return mux
 }
+
+func TestServer_SignalReady(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{Host: "127.0.0.1", Port: busy.Addr().(*net.TCPAddr).Port}
+
+	// the port is busy, so the listener is not bound and the server never becomes ready
+	runServer(config, func(chief uwe.Chief) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		if err := chief.WaitReady(ctx, "api"); err == nil {
+			t.Error("server is ready without the bound listener")
+		}
+	})
+
+	_ = busy.Close()
+	runServer(config, func(chief uwe.Chief) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := chief.WaitReady(ctx, "api"); err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := http.Get("http://" + config.TCPAddr() + "/")
+		if err != nil {
+			t.Fatalf("server is ready, but does not accept connections: %s", err)
+		}
+		_ = resp.Body.Close()
+	})
+}
+
+func runServer(config Config, locker func(chief uwe.Chief)) {
+	chief := uwe.NewChief()
+	chief.SetEventHandler(func(uwe.Event) {})
+	chief.AddWorker("api", NewServer(config, http.NotFoundHandler()))
+	chief.SetLocker(func() { locker(chief) })
+	chief.Run()
+}
@@ -0,0 +1,160 @@
+package uwe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReloadFailed is returned when the config can not be loaded
+// or any worker failed to apply it during the reload.
+var ErrReloadFailed = errors.New("reload failed")
+
+// ConfigLoader loads the actual configuration on each reload of the `Chief`.
+// The result is passed to the `Reloadable` workers as is, except the `TopologyConfig`:
+// in this case each worker receives the `WorkerParams` declared for it.
+type ConfigLoader func() (interface{}, error)
+
+// ReloadResult is a result of the `Chief` reload and the `ReloadAction` command.
+type ReloadResult struct {
+	// Reloaded are the `Reloadable` workers that applied the new config.
+	Reloaded []WorkerName `json:"reloaded,omitempty"`
+	// Restarted are the workers that were restarted with the `ReInitOnReload` option.
+	Restarted []WorkerName `json:"restarted,omitempty"`
+	// Failed are the workers that failed to reload with the error messages.
+	Failed map[WorkerName]string `json:"failed,omitempty"`
+}
+
+// SetConfigLoader sets the loader of the config which is passed to the `Reloadable` workers.
+func (c *chief) SetConfigLoader(loader ConfigLoader) Chief {
+	c.reloadMutex.Lock()
+	defer c.reloadMutex.Unlock()
+
+	c.configLoader = loader
+	return c
+}
+
+// Reload loads the new config and applies it to the running workers:
+// `Reloadable` workers receive the config, workers with the `ReInitOnReload` option
+// are restarted with the re-initialization, other workers are not touched.
+// The `ReInitOnReload` workers which are launched, but have not reached the `Run` yet, are skipped.
+func (c *chief) Reload() (ReloadResult, error) {
+	return c.reload(nil)
+}
+
+// reload applies the config to the running workers. The `parentConfig` is used,
+// if the `ConfigLoader` is not set, so the child supervisor receives the config of its parent.
+func (c *chief) reload(parentConfig interface{}) (ReloadResult, error) {
+	c.reloadMutex.Lock()
+	defer c.reloadMutex.Unlock()
+
+	result := ReloadResult{}
+
+	c.rtWorkersMutex.Lock()
+	running := c.rtWorkersLaunched && !stopInitiated(c.ctx)
+	ctx := c.ctx
+	c.rtWorkersMutex.Unlock()
+
+	if !running {
+		return result, ErrChiefNotRunning
+	}
+
+	config := parentConfig
+	if c.configLoader != nil {
+		var err error
+		if config, err = c.configLoader(); err != nil {
+			err = fmt.Errorf("%w: %s", ErrReloadFailed, err)
+			c.emit(Event{
+				Level: LvlError, Kind: KindReloadFailed,
+				Message: "Failed to load the config for the reload",
+				Fields:  map[string]interface{}{"error": err.Error()},
+				Err:     err,
+			})
+			return result, err
+		}
+	}
+
+	for _, name := range c.wPool.workersList() {
+		w := c.wPool.getWorker(name)
+		if w == nil {
+			continue
+		}
+		if launched, _ := c.wPool.isLaunched(name); !launched {
+			continue
+		}
+
+		var err error
+		switch worker := w.worker.(type) {
+		case Reloadable:
+			if err = worker.Reload(ctx, workerConfig(config, name)); err == nil {
+				result.Reloaded = append(result.Reloaded, name)
+			}
+		default:
+			if w.reloadMode != ReInitOnReload {
+				continue
+			}
+			err = c.restartWorker(name, true)
+			if errors.Is(err, ErrWorkerNotRunning) {
+				// the worker has not reached the `Run` yet, so there is nothing to restart
+				continue
+			}
+			if err == nil {
+				result.Restarted = append(result.Restarted, name)
+			}
+		}
+
+		if err != nil {
+			if result.Failed == nil {
+				result.Failed = map[WorkerName]string{}
+			}
+			result.Failed[name] = err.Error()
+
+			c.emit(Event{
+				Level: LvlError, Kind: KindWorkerReloadFailed, Worker: name,
+				Message: "Worker failed to reload",
+				Fields:  map[string]interface{}{"error": err.Error()},
+				Err:     err,
+			})
+			continue
+		}
+
+		c.emit(Event{
+			Level: LvlInfo, Kind: KindWorkerReloaded, Worker: name,
+			Message: "Worker reloaded",
+		})
+	}
+
+	event := Event{
+		Level: LvlInfo, Kind: KindReloadCompleted,
+		Message: "Reload completed",
+		Fields: map[string]interface{}{
+			"reloaded":  len(result.Reloaded),
+			"restarted": len(result.Restarted),
+			"failed":    len(result.Failed),
+		},
+	}
+
+	var err error
+	if len(result.Failed) > 0 {
+		err = fmt.Errorf("%w: %d worker(s) failed", ErrReloadFailed, len(result.Failed))
+		event.Level = LvlWarn
+		event.Err = err
+	}
+
+	c.emit(event)
+	return result, err
+}
+
+// workerConfig returns the part of the config intended for the worker.
+func workerConfig(config interface{}, name WorkerName) interface{} {
+	topology, ok := config.(TopologyConfig)
+	if !ok {
+		return config
+	}
+
+	for _, wc := range topology.Workers {
+		if WorkerName(wc.Name) == name {
+			return wc.Params
+		}
+	}
+	return WorkerParams(nil)
+}
@@ -0,0 +1,246 @@
+package uwe
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// SignalAction is an action of the `Chief` triggered by the OS signal.
+type SignalAction string
+
+const (
+	// SignalShutdown gracefully stops the `Chief`. The second shutdown signal
+	// received during the graceful shutdown forces the exit of the process.
+	SignalShutdown SignalAction = "shutdown"
+	// SignalReload triggers the reload of the `Chief`, same as the `(Chief).Reload()` call.
+	SignalReload SignalAction = "reload"
+	// SignalDump writes the states of all workers and the stacks of all goroutines
+	// into the `SignalHandler.DumpFile` or, if it is not set, into the event stream.
+	SignalDump SignalAction = "dump"
+	// SignalRotateLogs calls the `SignalHandler.RotateLogs` callback.
+	SignalRotateLogs SignalAction = "rotate_logs"
+)
+
+// SignalHandler configures the handling of the OS signals by the `Chief`.
+type SignalHandler struct {
+	// Signals maps the OS signals to the actions.
+	Signals map[os.Signal]SignalAction
+	// DisableForceExit disables the forced exit of the process
+	// on the second shutdown signal during the graceful shutdown.
+	DisableForceExit bool
+	// Exit terminates the process on the forced exit. By default, the `os.Exit` is used.
+	Exit func(code int)
+	// DumpFile is a path of the file to which the dumps are appended.
+	// If it is empty, the dump is emitted as the event.
+	DumpFile string
+	// RotateLogs is called on the `SignalRotateLogs` action.
+	RotateLogs func() error
+}
+
+// DefaultSignals returns the default map of the signals:
+// SIGTERM and SIGINT stop the `Chief`, SIGHUP triggers the reload
+// and, on the Unix systems, SIGUSR1 writes the dump.
+func DefaultSignals() map[os.Signal]SignalAction {
+	signals := map[os.Signal]SignalAction{
+		syscall.SIGTERM: SignalShutdown,
+		syscall.SIGINT:  SignalShutdown,
+		syscall.SIGHUP:  SignalReload,
+	}
+	addPlatformSignals(signals)
+	return signals
+}
+
+// DefaultSignalHandler returns the `SignalHandler` which is used when neither `Locker` nor `SignalHandler` is set.
+func DefaultSignalHandler() SignalHandler {
+	return SignalHandler{Signals: DefaultSignals()}
+}
+
+// SetSignalHandler sets the handling of the OS signals.
+// Unlike the default one, it is active even if the custom `Locker` is set.
+func (c *chief) SetSignalHandler(handler SignalHandler) Chief {
+	c.signalHandler = &handler
+	return c
+}
+
+// signalListener handles the OS signals during the single run of the `Chief`.
+type signalListener struct {
+	handler SignalHandler
+	signals chan os.Signal
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// listenSignals starts the handling of the OS signals, if it is required.
+// Signals are subscribed synchronously, so any signal sent after the call is handled.
+func (c *chief) listenSignals() *signalListener {
+	var handler SignalHandler
+	switch {
+	case c.signalHandler != nil:
+		handler = *c.signalHandler
+	case c.locker == nil:
+		handler = DefaultSignalHandler()
+	default:
+		return nil
+	}
+
+	if handler.Signals == nil {
+		handler.Signals = DefaultSignals()
+	}
+	if handler.Exit == nil {
+		handler.Exit = os.Exit
+	}
+
+	l := &signalListener{
+		handler: handler,
+		signals: make(chan os.Signal, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	list := make([]os.Signal, 0, len(handler.Signals))
+	for sig := range handler.Signals {
+		list = append(list, sig)
+	}
+	signal.Notify(l.signals, list...)
+
+	go c.handleSignals(l)
+	return l
+}
+
+// close stops the handling of the OS signals.
+func (l *signalListener) close() {
+	if l == nil {
+		return
+	}
+
+	signal.Stop(l.signals)
+	close(l.stop)
+	<-l.stopped
+}
+
+func (c *chief) handleSignals(l *signalListener) {
+	defer close(l.stopped)
+
+	shuttingDown := false
+	for {
+		var sig os.Signal
+		select {
+		case sig = <-l.signals:
+		case <-l.stop:
+			return
+		}
+
+		action := l.handler.Signals[sig]
+		c.emit(Event{
+			Level: LvlInfo, Kind: KindSignalReceived,
+			Message: "Signal received",
+			Fields:  map[string]interface{}{"signal": sig.String(), "action": string(action)},
+		})
+
+		switch action {
+		case SignalShutdown:
+			if shuttingDown && !l.handler.DisableForceExit {
+				c.forceExit(l.handler, sig)
+				return
+			}
+
+			shuttingDown = true
+			c.setStopCause(&StopError{Reason: StopBySignal})
+			c.Shutdown()
+
+		case SignalReload:
+			go func() { _, _ = c.reload(nil) }()
+
+		case SignalDump:
+			c.dump(l.handler.DumpFile)
+
+		case SignalRotateLogs:
+			if l.handler.RotateLogs == nil {
+				continue
+			}
+			if err := l.handler.RotateLogs(); err != nil {
+				c.emit(ErrorEventFrom(fmt.Errorf("failed to rotate logs: %w", err)))
+			}
+		}
+	}
+}
+
+// forceExit terminates the process without waiting for the workers.
+// The emitted events are delivered before the exit.
+func (c *chief) forceExit(handler SignalHandler, sig os.Signal) {
+	c.emit(Event{
+		Level: LvlFatal, Kind: KindForceExit,
+		Message: "Second shutdown signal received, forcing the exit",
+		Fields:  map[string]interface{}{"signal": sig.String()},
+	})
+	c.events.close()
+
+	handler.Exit(ExitCodeForceExit)
+}
+
+// StateDump is a snapshot of the `Chief` written by the `SignalDump` action.
+type StateDump struct {
+	Time   time.Time `json:"time"`
+	State  StateInfo `json:"state"`
+	Stacks string    `json:"stacks"`
+}
+
+// dump writes the `StateDump` into the file or emits it as the event.
+func (c *chief) dump(path string) {
+	dump := StateDump{
+		Time:   time.Now(),
+		State:  c.stateInfo(AppInfo{}),
+		Stacks: allStacks(),
+	}
+
+	if path == "" {
+		c.emit(Event{
+			Level: LvlInfo, Kind: KindStateDump,
+			Message: "State dump",
+			Fields:  map[string]interface{}{"state": dump.State, "stacks": dump.Stacks},
+		})
+		return
+	}
+
+	if err := appendDump(path, dump); err != nil {
+		c.emit(ErrorEventFrom(fmt.Errorf("failed to write state dump: %w", err)))
+		return
+	}
+
+	c.emit(Event{
+		Level: LvlInfo, Kind: KindStateDump,
+		Message: "State dump written",
+		Fields:  map[string]interface{}{"file": path},
+	})
+}
+
+// appendDump appends the dump as the JSON line to the file.
+func appendDump(path string, dump StateDump) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	err = json.NewEncoder(file).Encode(dump)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// allStacks returns the stacks of all goroutines.
+func allStacks() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,144 @@
+//go:build !windows
+// +build !windows
+
+package uwe
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// slowStopWorker keeps running after the stop signal until it is released.
+type slowStopWorker struct {
+	reloadableWorker
+	release chan struct{}
+}
+
+func (w *slowStopWorker) Run(ctx Context) error {
+	SignalReady(ctx)
+	<-ctx.Done()
+	<-w.release
+	return nil
+}
+
+func TestChief_SignalHandler(t *testing.T) {
+	worker := &slowStopWorker{release: make(chan struct{})}
+	dumpFile := filepath.Join(t.TempDir(), "dump.jsonl")
+	rotated := make(chan struct{}, 1)
+	exitCode := make(chan int, 1)
+
+	events := make(chan Event, 100)
+	chief := NewChief()
+	chief.AddEventHandler(func(e Event) { events <- e })
+	chief.AddWorker("slow", worker)
+	chief.SetForceStopTimeout(10 * time.Second)
+	chief.SetSignalHandler(SignalHandler{
+		Signals: map[os.Signal]SignalAction{
+			syscall.SIGTERM: SignalShutdown,
+			syscall.SIGHUP:  SignalReload,
+			syscall.SIGUSR1: SignalDump,
+			syscall.SIGUSR2: SignalRotateLogs,
+		},
+		Exit:       func(code int) { exitCode <- code },
+		DumpFile:   dumpFile,
+		RotateLogs: func() error { rotated <- struct{}{}; return nil },
+	})
+
+	done := make(chan error)
+	go func() { done <- chief.RunE() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := chief.WaitReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	self := os.Getpid()
+	_ = syscall.Kill(self, syscall.SIGUSR1)
+	waitEvent(t, events, KindStateDump)
+
+	data, err := os.ReadFile(dumpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dump StateDump
+	if err = json.Unmarshal(data, &dump); err != nil {
+		t.Fatal(err)
+	}
+	if dump.State.Workers["slow"] != WStateReady || dump.Stacks == "" {
+		t.Errorf("unexpected dump: %+v", dump.State)
+	}
+
+	_ = syscall.Kill(self, syscall.SIGHUP)
+	waitEvent(t, events, KindReloadCompleted)
+	if len(worker.configs) != 1 {
+		t.Errorf("worker was not reloaded")
+	}
+
+	_ = syscall.Kill(self, syscall.SIGUSR2)
+	select {
+	case <-rotated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logs were not rotated")
+	}
+
+	// the second signal must be sent after the first one is handled, otherwise they are coalesced
+	_ = syscall.Kill(self, syscall.SIGTERM)
+	for {
+		if e := waitEvent(t, events, KindSignalReceived); e.Fields["action"] == string(SignalShutdown) {
+			break
+		}
+	}
+	_ = syscall.Kill(self, syscall.SIGTERM)
+
+	select {
+	case code := <-exitCode:
+		if code != ExitCodeForceExit {
+			t.Errorf("exit code(%d) != %d", code, ExitCodeForceExit)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second signal did not force the exit")
+	}
+
+	close(worker.release)
+	if err = <-done; err != nil {
+		t.Errorf("unexpected stop error: %s", err)
+	}
+}
+
+func TestChief_DefaultSignalHandler(t *testing.T) {
+	events := make(chan Event, 100)
+	chief := NewChief()
+	chief.AddEventHandler(func(e Event) { events <- e })
+	chief.AddWorker("reloadable", &reloadableWorker{})
+
+	done := make(chan error)
+	go func() { done <- chief.RunE() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := chief.WaitReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_ = syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	dump := waitEvent(t, events, KindStateDump)
+	if _, ok := dump.Fields["stacks"]; !ok {
+		t.Errorf("stacks are not included into the dump event: %v", dump.Fields)
+	}
+
+	_ = syscall.Kill(os.Getpid(), syscall.SIGINT)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected stop error: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("chief was not stopped by the signal")
+	}
+}
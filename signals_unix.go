@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package uwe
+
+import (
+	"os"
+	"syscall"
+)
+
+func addPlatformSignals(signals map[os.Signal]SignalAction) {
+	signals[syscall.SIGUSR1] = SignalDump
+}
@@ -0,0 +1,9 @@
+//go:build windows
+// +build windows
+
+package uwe
+
+import "os"
+
+// addPlatformSignals does nothing, because Windows has no user-defined signals.
+func addPlatformSignals(map[os.Signal]SignalAction) {}
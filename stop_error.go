@@ -0,0 +1,82 @@
+package uwe
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StopReason describes why the `Chief` stopped.
+type StopReason string
+
+const (
+	// StopBySignal means that the `Chief` was stopped by the `Locker`,
+	// which by default waits for SIGTERM or SIGINT, or by the `Shutdown` call.
+	StopBySignal StopReason = "signal"
+	// StopByFatalWorker means that the worker with the `StopAppOnFail` strategy failed,
+	// or the worker exceeded the `RestartLimit` with enabled escalation.
+	StopByFatalWorker StopReason = "fatal_worker"
+	// StopByInitFailure means that the `Chief` failed to start,
+	// or the worker with the `StopAppOnFail` strategy failed to initialize.
+	StopByInitFailure StopReason = "init_failure"
+	// StopNoWorkers means that there are no workers to run.
+	StopNoWorkers StopReason = "no_workers"
+)
+
+// Exit codes returned by the `ExitCode` for each `StopReason`.
+const (
+	ExitCodeOK          = 0
+	ExitCodeFatalWorker = 1
+	ExitCodeInitFailure = 2
+	ExitCodeNoWorkers   = 3
+	// ExitCodeForceExit is passed to the `SignalHandler.Exit` on the second shutdown signal.
+	ExitCodeForceExit     = 130
+	ExitCodeUnknownReason = 70
+)
+
+// StopError describes the abnormal stop of the `Chief`.
+// It is returned by the `(Chief).RunE()`.
+type StopError struct {
+	Reason StopReason
+	Worker WorkerName
+	Err    error
+}
+
+func (e *StopError) Error() string {
+	if e.Worker != "" {
+		return fmt.Sprintf("chief stopped (%s) due to worker(%s): %s", e.Reason, e.Worker, e.Err)
+	}
+	return fmt.Sprintf("chief stopped (%s): %s", e.Reason, e.Err)
+}
+
+func (e *StopError) Unwrap() error { return e.Err }
+
+// ExitCode returns the process exit code corresponding to the stop reason.
+func (e *StopError) ExitCode() int {
+	switch e.Reason {
+	case StopBySignal:
+		return ExitCodeOK
+	case StopByFatalWorker:
+		return ExitCodeFatalWorker
+	case StopByInitFailure:
+		return ExitCodeInitFailure
+	case StopNoWorkers:
+		return ExitCodeNoWorkers
+	default:
+		return ExitCodeUnknownReason
+	}
+}
+
+// ExitCode returns the process exit code for the result of the `(Chief).RunE()`:
+//
+//	os.Exit(uwe.ExitCode(chief.RunE()))
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitCodeOK
+	}
+
+	var stopErr *StopError
+	if errors.As(err, &stopErr) {
+		return stopErr.ExitCode()
+	}
+	return ExitCodeUnknownReason
+}
@@ -0,0 +1,88 @@
+package uwe
+
+import (
+	"context"
+	"sync"
+)
+
+// stateTreeProvider is implemented by workers that supervise other workers.
+type stateTreeProvider interface {
+	stateInfo() StateInfo
+}
+
+// supervisorWorker is a `Worker` that runs the child `Chief`.
+type supervisorWorker struct {
+	child Chief
+
+	// emitter delivers the events of the child to the parent, it is updated on each run.
+	mutex   sync.Mutex
+	emitter eventEmitter
+}
+
+// NewSupervisorWorker wraps the `Chief` into the `Worker`,
+// so it can be launched by the parent `Chief` as a child supervisor.
+// The lifecycle of the child follows the `Context` of the worker:
+// the child is stopped when the parent stops the worker.
+// Events of the child are delivered into the parent event stream
+// with the worker names prefixed by the name of this worker, e.g. "child/worker".
+// States of the child workers are included into the `StatusAction` result of the parent.
+// The reload of the parent is propagated to the child.
+//
+// The child `Chief` must not be configured with own `Locker`, it is replaced during the run.
+// The event handlers of the child are kept, they receive the events along with the parent.
+func NewSupervisorWorker(child Chief) Worker {
+	s := &supervisorWorker{child: child}
+	child.AddEventHandler(s.forwardEvent)
+	return s
+}
+
+// Run starts the child `Chief` and blocks until the worker context is done.
+// It returns an error if the child stopped abnormally, e.g. due to a fatal worker.
+func (s *supervisorWorker) Run(ctx Context) error {
+	emitter, _ := ctx.(eventEmitter)
+	s.mutex.Lock()
+	s.emitter = emitter
+	s.mutex.Unlock()
+
+	s.child.SetContext(ctx)
+	s.child.SetLocker(func() { <-ctx.Done() })
+
+	// the child supervisor is ready when all its workers are ready
+	go func() {
+		if err := s.child.WaitReady(ctx); err == nil {
+			SignalReady(ctx)
+		}
+	}()
+
+	return s.child.RunE()
+}
+
+// forwardEvent delivers the event of the child to the parent event stream.
+func (s *supervisorWorker) forwardEvent(event Event) {
+	s.mutex.Lock()
+	emitter := s.emitter
+	s.mutex.Unlock()
+
+	if emitter != nil {
+		emitter.emitEvent(event)
+	}
+}
+
+// Reload reloads the child `Chief`. If the child has no own `ConfigLoader`,
+// its workers receive the config passed by the parent.
+func (s *supervisorWorker) Reload(_ context.Context, config interface{}) error {
+	if c, ok := s.child.(*chief); ok {
+		_, err := c.reload(config)
+		return err
+	}
+
+	_, err := s.child.Reload()
+	return err
+}
+
+func (s *supervisorWorker) stateInfo() StateInfo {
+	if c, ok := s.child.(*chief); ok {
+		return c.stateInfo(AppInfo{})
+	}
+	return StateInfo{Workers: s.child.GetWorkersStates()}
+}
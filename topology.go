@@ -0,0 +1,477 @@
+package uwe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownWorkerType is returned when the worker type is not registered in the `Registry`.
+	ErrUnknownWorkerType = errors.New("unknown worker type")
+	// ErrInvalidTopology is returned when the `TopologyConfig` is not valid.
+	ErrInvalidTopology = errors.New("invalid topology")
+	// ErrDependencyCycle is returned when the dependencies of the workers form a cycle.
+	ErrDependencyCycle = errors.New("dependency cycle detected")
+)
+
+// WorkerFactory creates the instance of the worker declared in the `TopologyConfig`.
+type WorkerFactory func(name WorkerName, params WorkerParams) (Worker, error)
+
+// WorkerParams are the arbitrary parameters of the worker instance declared in the config.
+type WorkerParams map[string]interface{}
+
+// Decode fills the `v` with the params. Params are passed through the JSON encoding,
+// so the `v` must use the `json` tags regardless of the config format.
+func (p WorkerParams) Decode(v interface{}) error {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// Duration is a `time.Duration` that can be decoded from the config
+// as the string in the `time.ParseDuration` format, e.g. "1m30s".
+type Duration time.Duration
+
+// UnmarshalText implements the `encoding.TextUnmarshaler`.
+func (d *Duration) UnmarshalText(text []byte) error {
+	dur, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(dur)
+	return nil
+}
+
+// MarshalText implements the `encoding.TextMarshaler`.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// TopologyConfig declares the workers of the `Chief`.
+type TopologyConfig struct {
+	// ForceStopTimeout overrides the `ForceStopTimeout` of the `Chief`, if it is set.
+	ForceStopTimeout Duration       `json:"force_stop_timeout,omitempty" yaml:"force_stop_timeout,omitempty" toml:"force_stop_timeout,omitempty"`
+	Groups           []GroupConfig  `json:"groups,omitempty" yaml:"groups,omitempty" toml:"groups,omitempty"`
+	Workers          []WorkerConfig `json:"workers" yaml:"workers" toml:"workers"`
+}
+
+// GroupConfig declares the worker group and its `SupervisionStrategy`:
+// "one_for_one" (default), "one_for_all" or "rest_for_one".
+type GroupConfig struct {
+	Name     string `json:"name" yaml:"name" toml:"name"`
+	Strategy string `json:"strategy,omitempty" yaml:"strategy,omitempty" toml:"strategy,omitempty"`
+}
+
+// WorkerConfig declares the instance of the worker.
+type WorkerConfig struct {
+	// Name is a unique name of the worker instance.
+	Name string `json:"name" yaml:"name" toml:"name"`
+	// Type is a name of the `WorkerFactory` in the `Registry`. By default, the Name is used.
+	Type string `json:"type,omitempty" yaml:"type,omitempty" toml:"type,omitempty"`
+	// Enabled allows to exclude the worker from the topology. By default, the worker is enabled.
+	Enabled *bool `json:"enabled,omitempty" yaml:"enabled,omitempty" toml:"enabled,omitempty"`
+	// Restart is a `RestartOption` of the worker, see the `ParseRestartOption`.
+	Restart      string              `json:"restart,omitempty" yaml:"restart,omitempty" toml:"restart,omitempty"`
+	Backoff      *BackoffConfig      `json:"backoff,omitempty" yaml:"backoff,omitempty" toml:"backoff,omitempty"`
+	RestartLimit *RestartLimitConfig `json:"restart_limit,omitempty" yaml:"restart_limit,omitempty" toml:"restart_limit,omitempty"`
+	DependsOn    []string            `json:"depends_on,omitempty" yaml:"depends_on,omitempty" toml:"depends_on,omitempty"`
+	Group        string              `json:"group,omitempty" yaml:"group,omitempty" toml:"group,omitempty"`
+	StopTimeout  Duration            `json:"stop_timeout,omitempty" yaml:"stop_timeout,omitempty" toml:"stop_timeout,omitempty"`
+	Watchdog     Duration            `json:"watchdog,omitempty" yaml:"watchdog,omitempty" toml:"watchdog,omitempty"`
+	HealthCheck  *HealthCheckConfig  `json:"health_check,omitempty" yaml:"health_check,omitempty" toml:"health_check,omitempty"`
+	Mailbox      *MailboxConfig      `json:"mailbox,omitempty" yaml:"mailbox,omitempty" toml:"mailbox,omitempty"`
+	// Params are passed to the `WorkerFactory`.
+	Params WorkerParams `json:"params,omitempty" yaml:"params,omitempty" toml:"params,omitempty"`
+}
+
+// IsEnabled returns `true` if the worker is not disabled explicitly.
+func (c WorkerConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// WorkerType returns the name of the `WorkerFactory` of the worker.
+func (c WorkerConfig) WorkerType() string {
+	if c.Type == "" {
+		return c.Name
+	}
+	return c.Type
+}
+
+// BackoffConfig is a config representation of the `BackoffOption`.
+type BackoffConfig struct {
+	InitialDelay Duration `json:"initial_delay,omitempty" yaml:"initial_delay,omitempty" toml:"initial_delay,omitempty"`
+	Multiplier   float64  `json:"multiplier,omitempty" yaml:"multiplier,omitempty" toml:"multiplier,omitempty"`
+	MaxDelay     Duration `json:"max_delay,omitempty" yaml:"max_delay,omitempty" toml:"max_delay,omitempty"`
+	Jitter       float64  `json:"jitter,omitempty" yaml:"jitter,omitempty" toml:"jitter,omitempty"`
+	ResetAfter   Duration `json:"reset_after,omitempty" yaml:"reset_after,omitempty" toml:"reset_after,omitempty"`
+}
+
+// RestartLimitConfig is a config representation of the `RestartLimit`.
+type RestartLimitConfig struct {
+	MaxRestarts int      `json:"max_restarts" yaml:"max_restarts" toml:"max_restarts"`
+	Window      Duration `json:"window" yaml:"window" toml:"window"`
+	Escalate    bool     `json:"escalate,omitempty" yaml:"escalate,omitempty" toml:"escalate,omitempty"`
+}
+
+// HealthCheckConfig is a config representation of the `HealthCheckOption`.
+type HealthCheckConfig struct {
+	Interval     Duration `json:"interval,omitempty" yaml:"interval,omitempty" toml:"interval,omitempty"`
+	Timeout      Duration `json:"timeout,omitempty" yaml:"timeout,omitempty" toml:"timeout,omitempty"`
+	RestartAfter int      `json:"restart_after,omitempty" yaml:"restart_after,omitempty" toml:"restart_after,omitempty"`
+}
+
+// MailboxConfig is a config representation of the `MailboxOption`.
+type MailboxConfig struct {
+	Capacity int `json:"capacity" yaml:"capacity" toml:"capacity"`
+	// Overflow is a `MailboxOverflow` of the mailbox, see the `ParseMailboxOverflow`.
+	Overflow string `json:"overflow,omitempty" yaml:"overflow,omitempty" toml:"overflow,omitempty"`
+}
+
+// options converts the config into the `WorkerOpts`.
+func (c WorkerConfig) options() ([]WorkerOpts, error) {
+	restart, err := ParseRestartOption(c.Restart)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []WorkerOpts{restart}
+	if c.Backoff != nil {
+		opts = append(opts, BackoffOption{
+			InitialDelay: time.Duration(c.Backoff.InitialDelay),
+			Multiplier:   c.Backoff.Multiplier,
+			MaxDelay:     time.Duration(c.Backoff.MaxDelay),
+			Jitter:       c.Backoff.Jitter,
+			ResetAfter:   time.Duration(c.Backoff.ResetAfter),
+		})
+	}
+	if c.RestartLimit != nil {
+		if c.RestartLimit.MaxRestarts < 0 || c.RestartLimit.Window <= 0 {
+			return nil, errors.New("restart limit requires non-negative max_restarts and positive window")
+		}
+		opts = append(opts, RestartLimit{
+			MaxRestarts: c.RestartLimit.MaxRestarts,
+			Window:      time.Duration(c.RestartLimit.Window),
+			Escalate:    c.RestartLimit.Escalate,
+		})
+	}
+	if len(c.DependsOn) > 0 {
+		deps := make(Dependencies, 0, len(c.DependsOn))
+		for _, dep := range c.DependsOn {
+			deps = append(deps, WorkerName(dep))
+		}
+		opts = append(opts, deps)
+	}
+	if c.Group != "" {
+		opts = append(opts, GroupName(c.Group))
+	}
+	if c.StopTimeout > 0 {
+		opts = append(opts, StopTimeout(c.StopTimeout))
+	}
+	if c.Watchdog > 0 {
+		opts = append(opts, Watchdog(c.Watchdog))
+	}
+	if c.HealthCheck != nil {
+		opts = append(opts, HealthCheckOption{
+			Interval:     time.Duration(c.HealthCheck.Interval),
+			Timeout:      time.Duration(c.HealthCheck.Timeout),
+			RestartAfter: c.HealthCheck.RestartAfter,
+		})
+	}
+	if c.Mailbox != nil {
+		overflow, err := ParseMailboxOverflow(c.Mailbox.Overflow)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, MailboxOption{Capacity: c.Mailbox.Capacity, Overflow: overflow})
+	}
+
+	return opts, nil
+}
+
+// ParseRestartOption returns the `RestartOption` by its config name:
+// "no" or empty string for `NoRestart`, "on_fail" for `RestartOnFail`, "on_error" for `RestartOnError`,
+// "restart" for `Restart`, "restart_and_reinit" for `RestartAndReInit` and "stop_app" for `StopAppOnFail`.
+func ParseRestartOption(s string) (RestartOption, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "no":
+		return NoRestart, nil
+	case "on_fail":
+		return RestartOnFail, nil
+	case "on_error":
+		return RestartOnError, nil
+	case "restart":
+		return Restart, nil
+	case "restart_and_reinit":
+		return RestartAndReInit, nil
+	case "stop_app":
+		return StopAppOnFail, nil
+	default:
+		return 0, fmt.Errorf("unknown restart option(%s)", s)
+	}
+}
+
+// ParseSupervisionStrategy returns the `SupervisionStrategy` by its config name:
+// "one_for_one" or empty string, "one_for_all" and "rest_for_one".
+// Names returned by the `(SupervisionStrategy).String()` are accepted as well.
+func ParseSupervisionStrategy(s string) (SupervisionStrategy, error) {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "-", "_") {
+	case "", "one_for_one":
+		return OneForOne, nil
+	case "one_for_all":
+		return OneForAll, nil
+	case "rest_for_one":
+		return RestForOne, nil
+	default:
+		return 0, fmt.Errorf("unknown supervision strategy(%s)", s)
+	}
+}
+
+// DecodeTopologyJSON reads the `TopologyConfig` in the JSON format.
+// Unknown fields are rejected to catch the typos in the config.
+func DecodeTopologyJSON(r io.Reader) (TopologyConfig, error) {
+	var cfg TopologyConfig
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("%w: %s", ErrInvalidTopology, err)
+	}
+	return cfg, nil
+}
+
+// TopologyError holds all problems found in the `TopologyConfig`.
+// It matches the `ErrInvalidTopology` and each of the wrapped errors with the `errors.Is` and `errors.As`,
+// the wrapped errors are walked by the `Is` and `As` methods, so it does not depend on the Go version.
+type TopologyError struct {
+	Errors []error
+}
+
+func (e *TopologyError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidTopology, strings.Join(msgs, "; "))
+}
+
+func (e *TopologyError) Is(target error) bool {
+	if target == ErrInvalidTopology {
+		return true
+	}
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *TopologyError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *TopologyError) Unwrap() []error {
+	return e.Errors
+}
+
+// Registry holds the `WorkerFactory` of each worker type and builds the `Chief` from the `TopologyConfig`.
+type Registry struct {
+	mutex     sync.RWMutex
+	factories map[string]WorkerFactory
+}
+
+// NewRegistry returns the empty `Registry`.
+func NewRegistry() *Registry {
+	return &Registry{factories: map[string]WorkerFactory{}}
+}
+
+// Register adds the factory of the worker type.
+// It returns an error if the type is already registered.
+func (r *Registry) Register(typeName string, factory WorkerFactory) error {
+	if typeName == "" || factory == nil {
+		return errors.New("worker type and factory are required")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.factories[typeName]; ok {
+		return fmt.Errorf("%s: worker type is already registered", typeName)
+	}
+
+	r.factories[typeName] = factory
+	return nil
+}
+
+// Types returns the sorted list of the registered worker types.
+func (r *Registry) Types() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	types := make([]string, 0, len(r.factories))
+	for typeName := range r.factories {
+		types = append(types, typeName)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (r *Registry) factory(typeName string) (WorkerFactory, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	factory, ok := r.factories[typeName]
+	return factory, ok
+}
+
+// Validate checks the `TopologyConfig` without creating the workers.
+// It returns the `*TopologyError` with all found problems:
+// missing or duplicate names, unknown worker types, groups and dependencies,
+// dependencies on the disabled workers, dependency cycles and invalid options.
+func (r *Registry) Validate(cfg TopologyConfig) error {
+	var errs []error
+
+	groups := make(map[string]struct{}, len(cfg.Groups))
+	for i, group := range cfg.Groups {
+		if group.Name == "" {
+			errs = append(errs, fmt.Errorf("groups[%d]: name is required", i))
+			continue
+		}
+		if _, ok := groups[group.Name]; ok {
+			errs = append(errs, fmt.Errorf("group %s: duplicate name", group.Name))
+		}
+		groups[group.Name] = struct{}{}
+
+		if _, err := ParseSupervisionStrategy(group.Strategy); err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", group.Name, err))
+		}
+	}
+
+	enabled := map[WorkerName]bool{}
+	for i, wc := range cfg.Workers {
+		if wc.Name == "" {
+			errs = append(errs, fmt.Errorf("workers[%d]: name is required", i))
+			continue
+		}
+		if _, ok := enabled[WorkerName(wc.Name)]; ok {
+			errs = append(errs, fmt.Errorf("%s: duplicate worker name", wc.Name))
+		}
+		enabled[WorkerName(wc.Name)] = wc.IsEnabled()
+	}
+
+	deps := map[WorkerName][]WorkerName{}
+	for _, wc := range cfg.Workers {
+		if wc.Name == "" || !wc.IsEnabled() {
+			continue
+		}
+		name := WorkerName(wc.Name)
+
+		if _, ok := r.factory(wc.WorkerType()); !ok {
+			errs = append(errs, fmt.Errorf("%s: %w(%s)", name, ErrUnknownWorkerType, wc.WorkerType()))
+		}
+		if _, err := wc.options(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		if _, ok := groups[wc.Group]; wc.Group != "" && !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown group(%s)", name, wc.Group))
+		}
+
+		deps[name] = nil
+		for _, dep := range wc.DependsOn {
+			isEnabled, ok := enabled[WorkerName(dep)]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("%s: depends on unknown worker(%s)", name, dep))
+			case !isEnabled:
+				errs = append(errs, fmt.Errorf("%s: depends on disabled worker(%s)", name, dep))
+			default:
+				deps[name] = append(deps[name], WorkerName(dep))
+			}
+		}
+	}
+
+	if err := checkDependencies(deps); err != nil {
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return &TopologyError{Errors: errs}
+	}
+	return nil
+}
+
+// Build returns the new `Chief` with the workers declared in the `TopologyConfig`.
+func (r *Registry) Build(cfg TopologyConfig) (Chief, error) {
+	chief := NewChief()
+	if err := r.Apply(chief, cfg); err != nil {
+		return nil, err
+	}
+	return chief, nil
+}
+
+// Apply validates the `TopologyConfig`, creates the enabled workers and adds them to the `chief`.
+// Nothing is added to the `chief` if the config is not valid or any factory fails.
+func (r *Registry) Apply(chief Chief, cfg TopologyConfig) error {
+	if err := r.Validate(cfg); err != nil {
+		return err
+	}
+
+	type instance struct {
+		name   WorkerName
+		worker Worker
+		opts   []WorkerOpts
+	}
+
+	var errs []error
+	instances := make([]instance, 0, len(cfg.Workers))
+	for _, wc := range cfg.Workers {
+		if !wc.IsEnabled() {
+			continue
+		}
+
+		name := WorkerName(wc.Name)
+		factory, _ := r.factory(wc.WorkerType())
+		worker, err := factory(name, wc.Params)
+		if err == nil && worker == nil {
+			err = errors.New("factory returned nil worker")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+
+		opts, _ := wc.options()
+		instances = append(instances, instance{name: name, worker: worker, opts: opts})
+	}
+
+	if len(errs) > 0 {
+		return &TopologyError{Errors: errs}
+	}
+
+	if cfg.ForceStopTimeout > 0 {
+		chief.SetForceStopTimeout(time.Duration(cfg.ForceStopTimeout))
+	}
+	for _, group := range cfg.Groups {
+		strategy, _ := ParseSupervisionStrategy(group.Strategy)
+		chief.AddWorkerGroup(GroupName(group.Name), strategy)
+	}
+	for _, inst := range instances {
+		chief.AddWorker(inst.name, inst.worker, inst.opts...)
+	}
+
+	return nil
+}
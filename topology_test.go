@@ -0,0 +1,141 @@
+package uwe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTopology = `{
+  "force_stop_timeout": "5s",
+  "groups": [{"name": "storage", "strategy": "one_for_all"}],
+  "workers": [
+    {"name": "db", "type": "recorder", "group": "storage", "stop_timeout": "1s"},
+    {"name": "api", "type": "recorder", "depends_on": ["db"], "restart": "restart",
+     "backoff": {"initial_delay": "10ms", "multiplier": 2}, "params": {"label": "api"}},
+    {"name": "consumer", "type": "recorder", "depends_on": ["api"], "restart": "restart_and_reinit",
+     "restart_limit": {"max_restarts": 3, "window": "1m"}},
+    {"name": "legacy", "type": "removed", "enabled": false}
+  ]
+}`
+
+func TestRegistry_Build(t *testing.T) {
+	recorder := &orderRecorder{}
+	labels := map[WorkerName]string{}
+
+	registry := NewRegistry()
+	err := registry.Register("recorder", func(name WorkerName, params WorkerParams) (Worker, error) {
+		var p struct {
+			Label string `json:"label"`
+		}
+		if err := params.Decode(&p); err != nil {
+			return nil, err
+		}
+		labels[name] = p.Label
+		return &recordingWorker{name: string(name), recorder: recorder}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Register("recorder", nil); err == nil {
+		t.Error("duplicate registration was accepted")
+	}
+
+	cfg, err := DecodeTopologyJSON(strings.NewReader(testTopology))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(cfg.Workers[0].StopTimeout) != time.Second {
+		t.Errorf("stop timeout(%s) != 1s", time.Duration(cfg.Workers[0].StopTimeout))
+	}
+
+	chief, err := registry.Build(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chief.SetLocker(waitFor(func() bool { return recorder.count() == 3 }))
+	chief.SetEventHandler(func(Event) {})
+
+	runChief(t, chief, 5*time.Second)
+
+	expected := "init:db init:api init:consumer stop:consumer stop:api stop:db"
+	if got := strings.Join(recorder.events, " "); got != expected {
+		t.Errorf("order(%s) != %s", got, expected)
+	}
+	if labels["api"] != "api" {
+		t.Errorf("params were not passed to the factory: %v", labels)
+	}
+	if _, ok := chief.GetWorkersStates()["legacy"]; ok {
+		t.Error("disabled worker was added")
+	}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register("noop", func(WorkerName, WorkerParams) (Worker, error) {
+		return &failingWorker{}, nil
+	})
+
+	disabled := false
+	cfg := TopologyConfig{
+		Groups: []GroupConfig{{Name: "group", Strategy: "all_for_nothing"}},
+		Workers: []WorkerConfig{
+			{Name: "noop"},
+			{Name: "noop"},
+			{Name: "unknown", Type: "missing"},
+			{Name: "off", Type: "noop", Enabled: &disabled},
+			{Name: "dependent", Type: "noop", DependsOn: []string{"off", "ghost"}, Group: "other"},
+			{Name: "bad_restart", Type: "noop", Restart: "sometimes"},
+			{Name: "bad_mailbox", Type: "noop", Mailbox: &MailboxConfig{Capacity: 8, Overflow: "explode"}},
+			{Name: "first", Type: "noop", DependsOn: []string{"second"}},
+			{Name: "second", Type: "noop", DependsOn: []string{"first"}},
+		},
+	}
+
+	err := registry.Validate(cfg)
+	if !errors.Is(err, ErrInvalidTopology) || !errors.Is(err, ErrUnknownWorkerType) || !errors.Is(err, ErrDependencyCycle) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if errors.Is(err, ErrWorkerNotRunning) {
+		t.Errorf("error matches the unrelated error: %v", err)
+	}
+	var topologyErr *TopologyError
+	if !errors.As(err, &topologyErr) || len(topologyErr.Errors) != 9 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the wrapped errors are matched without the multiple errors unwrapping
+	var stopErr *StopError
+	wrapped := &TopologyError{Errors: []error{io.EOF, fmt.Errorf("worker: %w", &StopError{Reason: StopNoWorkers})}}
+	if !errors.As(wrapped, &stopErr) || stopErr.Reason != StopNoWorkers || !errors.Is(wrapped, io.EOF) {
+		t.Errorf("wrapped errors are not matched: %v", wrapped)
+	}
+
+	for _, msg := range []string{
+		"group group: unknown supervision strategy(all_for_nothing)",
+		"noop: duplicate worker name",
+		"unknown: unknown worker type(missing)",
+		"dependent: depends on disabled worker(off)",
+		"dependent: depends on unknown worker(ghost)",
+		"dependent: unknown group(other)",
+		"bad_restart: unknown restart option(sometimes)",
+		"bad_mailbox: unknown mailbox overflow(explode)",
+		"dependency cycle detected: first -> second -> first",
+	} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("error does not contain %q: %s", msg, err)
+		}
+	}
+
+	if _, err := registry.Build(cfg); err == nil {
+		t.Error("invalid topology was built")
+	}
+
+	_, err = DecodeTopologyJSON(strings.NewReader(`{"workers": [{"name": "noop", "restart_mode": "no"}]}`))
+	if !errors.Is(err, ErrInvalidTopology) {
+		t.Errorf("unknown field was accepted: %v", err)
+	}
+}
@@ -1,9 +1,12 @@
 package uwe
 
 import (
+	"bytes"
 	"errors"
 	"log"
 	"reflect"
+	"runtime"
+	"strconv"
 )
 
 // WorkerExistRule is a custom validation rule for the validation libs.
@@ -46,15 +49,62 @@ func STDLogEventHandler() EventHandler {
 			level = "ERROR"
 		case LvlInfo:
 			level = "INFO"
+		case LvlDebug:
+			level = "DEBUG"
 		default:
 			level = "WARN"
 		}
 
-		if event.Fields == nil {
-			event.Fields = map[string]interface{}{}
+		// fields are copied, because the event is shared between all handlers
+		fields := make(map[string]interface{}, len(event.Fields)+2)
+		for k, v := range event.Fields {
+			fields[k] = v
 		}
-		event.Fields["worker"] = event.Worker
+		fields["worker"] = event.Worker
+		fields["kind"] = event.Kind
+		event.Fields = fields
 
 		log.Printf("%s: %s %s\n", level, event.Message, event.FormatFields())
 	}
 }
+
+// goroutineID returns the id of the current goroutine.
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// the first line of the stack has format "goroutine 42 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		buf = buf[:i]
+	}
+
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given id.
+// If the goroutine is not found, the stacks of all goroutines are returned.
+func goroutineStack(id uint64) string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	if id == 0 {
+		return string(buf)
+	}
+
+	prefix := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, prefix) {
+			return string(stack)
+		}
+	}
+
+	return string(buf)
+}
@@ -0,0 +1,698 @@
+// Package walbroker provides the durable `uwe.IMQBroker`, which keeps the messages
+// in the append-only log under the data directory, so they survive the restart of the process.
+//
+// Each message is written to the log before the delivery and stays there until the receiver
+// acknowledges it with the `Ack`. Messages which are not acknowledged are delivered again
+// when the worker is launched next time: after the restart of the worker or of the whole process.
+// So the delivery is at-least-once, and the receivers must tolerate the duplicates,
+// the redelivered messages are marked with the `MetaRedelivered`.
+//
+// Message data is stored with the `Codec` registered for the `uwe.MessageKind`,
+// by default it is JSON, see the `JSONCodec`. The encoded message is limited to 64 MiB,
+// the bigger messages are rejected with the `ErrRecordTooLarge`.
+//
+// The replies to the requests are not persisted, because the requester does not survive the restart.
+package walbroker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+)
+
+const (
+	// TargetAck is a target of the control message sent by the `Ack`.
+	TargetAck = "wal-ack"
+
+	// MetaSequence is a key of the `uwe.Message.Meta` with the sequence number of the message in the log.
+	MetaSequence = "uwe.wal.seq"
+	// MetaRedelivered is a key of the `uwe.Message.Meta`, which is set to "true"
+	// if the message could have been delivered before.
+	MetaRedelivered = "uwe.wal.redelivered"
+)
+
+const (
+	// DefaultCompactInterval is a default interval of the log compaction.
+	DefaultCompactInterval = time.Minute
+	// DefaultChanLen is a default buffer size of the channel of the messages sent by the workers.
+	DefaultChanLen = 64
+)
+
+var (
+	// ErrNotInitialized is returned when the log is accessed before the `Init` or after the `Serve` is finished.
+	ErrNotInitialized = errors.New("broker is not initialized")
+	// ErrLogDamaged is reported to the `ErrorHandler` by the `Init`, when the log has the invalid records.
+	// The error contains the number of the skipped records and the size of the truncated tail.
+	ErrLogDamaged = errors.New("log is damaged")
+)
+
+// Config is a configuration of the `Broker`.
+type Config struct {
+	// Dir is a data directory of the log, it is created if it does not exist.
+	Dir string
+	// ChanLen is a buffer size of the channel of the messages sent by the workers,
+	// by default the `DefaultChanLen` is used.
+	ChanLen int
+	// CompactInterval is an interval of the log compaction, by default the `DefaultCompactInterval` is used.
+	// The log is compacted only if some messages were acknowledged since the last compaction.
+	CompactInterval time.Duration
+	// NoSync disables the sync of the log after each write. It is faster,
+	// but the messages can be lost on the crash of the OS.
+	NoSync bool
+	// ErrorHandler receives the errors of the log and of the codecs,
+	// the message which can not be written or decoded is dropped.
+	// It is called synchronously by the broker, so it must not block.
+	ErrorHandler func(error)
+}
+
+// Broker is the durable `uwe.IMQBroker`. It supports the `uwe.RemovableBroker`, the `uwe.ObservableBroker`,
+// the `uwe.RequestBroker` and the `uwe.MailboxStats`.
+type Broker struct {
+	config   Config
+	messages chan *uwe.Message
+	requests *uwe.Correlator
+	topics   *uwe.Subscriptions
+	observer uwe.Observer
+
+	codecsMutex sync.RWMutex
+	codecs      map[uwe.MessageKind]Codec
+
+	// mutex guards the log and the queues.
+	mutex   sync.Mutex
+	log     *wal
+	lastSeq uint64
+	// acked is a number of the acknowledgements since the last compaction.
+	acked  int
+	queues map[uwe.WorkerName]*queue
+}
+
+// queue holds the unacknowledged messages to the worker.
+type queue struct {
+	// entries are ordered by the sequence number.
+	entries []*entry
+	// cursor is the sequence number of the last message delivered during the current launch of the worker.
+	cursor uint64
+	// in is a mailbox channel of the current launch, it is nil if the worker is not registered.
+	in      chan *uwe.Message
+	stop    chan struct{}
+	notify  chan struct{}
+	stopped bool
+}
+
+type entry struct {
+	rec       record
+	delivered bool
+}
+
+// New returns the `Broker`, the log is opened by the `Init`.
+func New(config Config) *Broker {
+	if config.ChanLen < 1 {
+		config.ChanLen = DefaultChanLen
+	}
+	if config.CompactInterval <= 0 {
+		config.CompactInterval = DefaultCompactInterval
+	}
+
+	return &Broker{
+		config:   config,
+		messages: make(chan *uwe.Message, config.ChanLen),
+		requests: uwe.NewCorrelator(),
+		topics:   uwe.NewSubscriptions(),
+		observer: uwe.NopObserver{},
+		codecs:   map[uwe.MessageKind]Codec{},
+		queues:   map[uwe.WorkerName]*queue{},
+	}
+}
+
+// Ack acknowledges the message received from the `Broker`, so it is removed from the log.
+// It must be called with the bus of the receiver, usually it is the worker `uwe.Context`.
+func Ack(bus uwe.SenderBus, msg *uwe.Message) {
+	seq, ok := msg.Meta[MetaSequence]
+	if !ok {
+		return
+	}
+
+	uwe.SendMessage(bus, uwe.Message{Target: TargetAck, Meta: map[string]string{MetaSequence: seq}})
+}
+
+// RegisterCodec sets the `Codec` of the data of the messages with the kind.
+func (b *Broker) RegisterCodec(kind uwe.MessageKind, codec Codec) {
+	b.codecsMutex.Lock()
+	defer b.codecsMutex.Unlock()
+
+	b.codecs[kind] = codec
+}
+
+func (b *Broker) codec(kind uwe.MessageKind) Codec {
+	b.codecsMutex.RLock()
+	defer b.codecsMutex.RUnlock()
+
+	if codec, ok := b.codecs[kind]; ok {
+		return codec
+	}
+	return JSONCodec(nil)
+}
+
+// SetObserver implements the `uwe.ObservableBroker`.
+func (b *Broker) SetObserver(observer uwe.Observer) {
+	if observer == nil {
+		observer = uwe.NopObserver{}
+	}
+	b.observer = observer
+}
+
+// Init opens the log and restores the unacknowledged messages.
+func (b *Broker) Init() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.log != nil {
+		return nil
+	}
+
+	log, records, dmg, err := openWAL(b.config.Dir, b.config.NoSync)
+	if err != nil {
+		return fmt.Errorf("failed to open the log: %w", err)
+	}
+	if dmg.corrupted > 0 || dmg.truncated > 0 {
+		b.reportError(fmt.Errorf("%w: %s", ErrLogDamaged, dmg))
+	}
+
+	b.log = log
+	b.replay(records)
+	return nil
+}
+
+// replay restores the queues from the log records. It must be called with the locked mutex.
+func (b *Broker) replay(records []record) {
+	acked := map[uint64]bool{}
+	for _, rec := range records {
+		if rec.Op == opAck {
+			acked[rec.Seq] = true
+		}
+		if rec.Seq > b.lastSeq {
+			b.lastSeq = rec.Seq
+		}
+	}
+
+	for _, rec := range records {
+		if rec.Op != opPut {
+			continue
+		}
+		if acked[rec.Seq] {
+			b.acked++
+			continue
+		}
+
+		// messages from the previous run could have been delivered before the restart
+		q := b.queue(rec.Target)
+		q.entries = append(q.entries, &entry{rec: rec, delivered: true})
+	}
+}
+
+// queue returns the queue of the worker, it must be called with the locked mutex.
+func (b *Broker) queue(name uwe.WorkerName) *queue {
+	q, ok := b.queues[name]
+	if !ok {
+		q = &queue{}
+		b.queues[name] = q
+	}
+	return q
+}
+
+func (b *Broker) DefaultBus() uwe.SenderBus {
+	return uwe.NewRequestBus("", make(chan *uwe.Message, 1), b.messages, b.requests)
+}
+
+// AddWorker returns the mailbox of the worker, the unacknowledged messages are delivered to it again.
+func (b *Broker) AddWorker(name uwe.WorkerName) uwe.Mailbox {
+	in := make(chan *uwe.Message)
+	b.register(name, in)
+	return uwe.NewRequestBus(name, in, b.messages, b.requests)
+}
+
+// register starts the delivery of the messages to the channel, the previous delivery is stopped.
+func (b *Broker) register(name uwe.WorkerName, in chan *uwe.Message) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	q := b.queue(name)
+	if q.stop != nil {
+		close(q.stop)
+	}
+
+	q.in = in
+	q.stop = make(chan struct{})
+	q.notify = make(chan struct{}, 1)
+	q.cursor = 0
+	q.stopped = false
+
+	go b.pump(name, q, q.in, q.stop, q.notify)
+}
+
+// RemoveWorker implements the `uwe.RemovableBroker`. The unacknowledged messages of the worker
+// are dropped and acknowledged in the log, so the compaction removes them.
+func (b *Broker) RemoveWorker(name uwe.WorkerName) {
+	b.mutex.Lock()
+	if q, ok := b.queues[name]; ok {
+		if q.stop != nil {
+			close(q.stop)
+		}
+		delete(b.queues, name)
+		b.drop(name, q.entries)
+	}
+	b.mutex.Unlock()
+
+	b.requests.FailTarget(name, uwe.ErrTargetStopped)
+	b.topics.RemoveWorker(name)
+}
+
+// WorkerStopped implements the `uwe.RequestBroker`.
+func (b *Broker) WorkerStopped(name uwe.WorkerName) {
+	b.mutex.Lock()
+	if q, ok := b.queues[name]; ok {
+		q.stopped = true
+	}
+	b.mutex.Unlock()
+
+	b.requests.FailTarget(name, uwe.ErrTargetStopped)
+}
+
+// MailboxDepths implements the `uwe.MailboxStats`,
+// it returns the number of messages which are not delivered during the current launch of the worker.
+func (b *Broker) MailboxDepths() map[uwe.WorkerName]int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	r := make(map[uwe.WorkerName]int, len(b.queues))
+	for name, q := range b.queues {
+		for _, e := range q.entries {
+			if e.rec.Seq > q.cursor {
+				r[name]++
+			}
+		}
+	}
+	return r
+}
+
+// Serve routes the messages and compacts the log until the context is done, then the log is closed.
+func (b *Broker) Serve(ctx context.Context) {
+	ticker := time.NewTicker(b.config.CompactInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-b.messages:
+			if msg != nil {
+				b.route(msg)
+			}
+
+		case <-ticker.C:
+			if err := b.compact(false); err != nil {
+				b.reportError(fmt.Errorf("failed to compact the log: %w", err))
+			}
+
+		case <-ctx.Done():
+			b.shutdown()
+			return
+		}
+	}
+}
+
+// shutdown routes the messages left in the channel, e.g. the last acknowledgements, and closes the log.
+// The queues are dropped, the next `Init` restores them from the log.
+func (b *Broker) shutdown() {
+	for {
+		select {
+		case msg := <-b.messages:
+			if msg != nil {
+				b.route(msg)
+			}
+			continue
+		default:
+		}
+		break
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, q := range b.queues {
+		if q.stop != nil {
+			close(q.stop)
+		}
+	}
+	b.queues = map[uwe.WorkerName]*queue{}
+	b.acked = 0
+
+	if b.log != nil {
+		if err := b.log.close(); err != nil {
+			b.reportError(fmt.Errorf("failed to close the log: %w", err))
+		}
+		b.log = nil
+	}
+}
+
+// Compact rewrites the log, leaving only the unacknowledged messages.
+func (b *Broker) Compact() error {
+	return b.compact(true)
+}
+
+func (b *Broker) compact(force bool) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.log == nil {
+		return ErrNotInitialized
+	}
+	if b.acked == 0 && !force {
+		return nil
+	}
+
+	var records []record
+	for _, q := range b.queues {
+		for _, e := range q.entries {
+			records = append(records, e.rec)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Seq < records[j].Seq })
+
+	if err := b.log.rewrite(records); err != nil {
+		return err
+	}
+	b.acked = 0
+	return nil
+}
+
+func (b *Broker) route(msg *uwe.Message) {
+	if b.requests.Resolve(msg) {
+		return
+	}
+
+	switch msg.Target {
+	case TargetAck:
+		b.ack(msg)
+
+	case uwe.TargetSubscribe:
+		b.topics.Subscribe(msg.Sender, msg.Topic)
+
+	case uwe.TargetUnsubscribe:
+		b.topics.Unsubscribe(msg.Sender, msg.Topic)
+
+	case uwe.TargetSelfInit:
+		if in, ok := msg.Data.(chan *uwe.Message); ok {
+			b.register(msg.Sender, in)
+		}
+
+	case "":
+		if msg.Topic == "" {
+			b.observer.MessageDropped(msg.Sender, msg.Target)
+			return
+		}
+		b.store(msg, b.topics.Subscribers(msg.Topic), true)
+
+	case uwe.TargetBroadcast:
+		b.mutex.Lock()
+		targets := make([]uwe.WorkerName, 0, len(b.queues))
+		for name, q := range b.queues {
+			if q.in != nil {
+				targets = append(targets, name)
+			}
+		}
+		b.mutex.Unlock()
+
+		sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
+		b.store(msg, targets, false)
+
+	default:
+		b.store(msg, []uwe.WorkerName{msg.Target}, false)
+	}
+}
+
+// store writes the message to the log for each target and puts it into the queues.
+// If the `setTarget` is true, the target of each copy is set to the receiver, as for the published messages.
+func (b *Broker) store(msg *uwe.Message, targets []uwe.WorkerName, setTarget bool) {
+	data, err := b.codec(msg.Kind).Encode(msg.Data)
+	if err != nil {
+		b.reportError(fmt.Errorf("failed to encode the message of kind %d from %s: %w", msg.Kind, msg.Sender, err))
+		for _, to := range targets {
+			b.observer.MessageDropped(msg.Sender, to)
+		}
+		b.failRequest(msg, err)
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.log == nil {
+		b.failRequest(msg, ErrNotInitialized)
+		return
+	}
+
+	records := make([]record, 0, len(targets))
+	for _, to := range targets {
+		if to == msg.Sender && msg.Target != to {
+			continue
+		}
+
+		q, ok := b.queues[to]
+		if !ok || q.in == nil {
+			b.observer.MessageDropped(msg.Sender, to)
+			b.failRequest(msg, fmt.Errorf("%s: %w", to, uwe.ErrUnknownTarget))
+			continue
+		}
+		if q.stopped && msg.IsRequest() {
+			b.observer.MessageDropped(msg.Sender, to)
+			b.failRequest(msg, fmt.Errorf("%s: %w", to, uwe.ErrTargetStopped))
+			continue
+		}
+
+		target := msg.Target
+		if setTarget {
+			target = to
+		}
+
+		b.lastSeq++
+		records = append(records, record{
+			Op:            opPut,
+			Seq:           b.lastSeq,
+			Target:        to,
+			Sender:        msg.Sender,
+			Kind:          msg.Kind,
+			Topic:         msg.Topic,
+			CorrelationID: msg.CorrelationID,
+			Meta:          msg.Meta,
+			Data:          data,
+		})
+		// the original target is kept for the broadcast messages
+		if target != to {
+			records[len(records)-1].Meta = withMeta(msg.Meta, metaTarget, string(target))
+		}
+	}
+
+	if len(records) == 0 {
+		return
+	}
+
+	if err = b.log.append(records...); err != nil {
+		b.reportError(fmt.Errorf("failed to write the message to the log: %w", err))
+		for _, rec := range records {
+			b.observer.MessageDropped(rec.Sender, rec.Target)
+		}
+		b.failRequest(msg, err)
+		return
+	}
+
+	for _, rec := range records {
+		q := b.queues[rec.Target]
+		q.entries = append(q.entries, &entry{rec: rec})
+		b.observer.MessageSent(rec.Sender, rec.Target)
+
+		select {
+		case q.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// metaTarget keeps the original target of the message which differs from the receiver.
+const metaTarget = "uwe.wal.target"
+
+func withMeta(meta map[string]string, key, value string) map[string]string {
+	r := make(map[string]string, len(meta)+1)
+	for k, v := range meta {
+		r[k] = v
+	}
+	r[key] = value
+	return r
+}
+
+func (b *Broker) failRequest(msg *uwe.Message, err error) {
+	if msg.IsRequest() {
+		b.requests.Fail(msg.CorrelationID, err)
+	}
+}
+
+// ack removes the message from the queue and writes the acknowledgement to the log.
+func (b *Broker) ack(msg *uwe.Message) {
+	seq, err := strconv.ParseUint(msg.Meta[MetaSequence], 10, 64)
+	if err != nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.log == nil || !b.remove(msg.Sender, seq) {
+		return
+	}
+
+	if err = b.log.append(record{Op: opAck, Seq: seq}); err != nil {
+		b.reportError(fmt.Errorf("failed to write the acknowledgement to the log: %w", err))
+	}
+	b.acked++
+}
+
+// drop acknowledges the messages of the removed worker in the log. It must be called with the locked mutex.
+func (b *Broker) drop(name uwe.WorkerName, entries []*entry) {
+	if len(entries) == 0 {
+		return
+	}
+
+	records := make([]record, 0, len(entries))
+	for _, e := range entries {
+		b.observer.MessageDropped(e.rec.Sender, name)
+		records = append(records, record{Op: opAck, Seq: e.rec.Seq})
+	}
+
+	if b.log == nil {
+		return
+	}
+	if err := b.log.append(records...); err != nil {
+		b.reportError(fmt.Errorf("failed to write the acknowledgements of the removed worker %s to the log: %w", name, err))
+		return
+	}
+	b.acked += len(records)
+}
+
+// remove deletes the message from the queue of the receiver, which is usually the sender of the acknowledgement.
+// It must be called with the locked mutex.
+func (b *Broker) remove(receiver uwe.WorkerName, seq uint64) bool {
+	if q, ok := b.queues[receiver]; ok && q.remove(seq) {
+		return true
+	}
+
+	for _, q := range b.queues {
+		if q.remove(seq) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *queue) remove(seq uint64) bool {
+	i := sort.Search(len(q.entries), func(i int) bool { return q.entries[i].rec.Seq >= seq })
+	if i == len(q.entries) || q.entries[i].rec.Seq != seq {
+		return false
+	}
+
+	q.entries = append(q.entries[:i], q.entries[i+1:]...)
+	return true
+}
+
+// pump delivers the queued messages to the worker mailbox in the order of the sequence numbers.
+func (b *Broker) pump(name uwe.WorkerName, q *queue, in chan<- *uwe.Message, stop, notify <-chan struct{}) {
+	for {
+		msg, ok := b.next(name, q, stop)
+		if !ok {
+			select {
+			case <-notify:
+				continue
+			case <-stop:
+				return
+			}
+		}
+
+		select {
+		case in <- msg:
+			b.observer.MessageDelivered(msg.Sender, name)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// next returns the first message, which was not delivered during the current launch of the worker.
+func (b *Broker) next(name uwe.WorkerName, q *queue, stop <-chan struct{}) (*uwe.Message, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	select {
+	case <-stop:
+		return nil, false
+	default:
+	}
+
+	for _, e := range q.entries {
+		if e.rec.Seq <= q.cursor {
+			continue
+		}
+
+		q.cursor = e.rec.Seq
+		msg, err := b.message(e)
+		if err != nil {
+			// the message which can not be decoded would fail on each delivery
+			b.reportError(fmt.Errorf("failed to decode the message %d to %s: %w", e.rec.Seq, name, err))
+			q.remove(e.rec.Seq)
+			if err = b.log.append(record{Op: opAck, Seq: e.rec.Seq}); err == nil {
+				b.acked++
+			}
+			continue
+		}
+
+		e.delivered = true
+		return msg, true
+	}
+	return nil, false
+}
+
+// message restores the message from the log entry.
+func (b *Broker) message(e *entry) (*uwe.Message, error) {
+	data, err := b.codec(e.rec.Kind).Decode(e.rec.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	target := e.rec.Target
+	meta := withMeta(e.rec.Meta, MetaSequence, strconv.FormatUint(e.rec.Seq, 10))
+	if original, ok := meta[metaTarget]; ok {
+		target = uwe.WorkerName(original)
+		delete(meta, metaTarget)
+	}
+	if e.delivered {
+		meta[MetaRedelivered] = "true"
+	}
+
+	return &uwe.Message{
+		Target:        target,
+		Sender:        e.rec.Sender,
+		Kind:          e.rec.Kind,
+		Data:          data,
+		Meta:          meta,
+		CorrelationID: e.rec.CorrelationID,
+		Topic:         e.rec.Topic,
+	}, nil
+}
+
+func (b *Broker) reportError(err error) {
+	if b.config.ErrorHandler != nil {
+		b.config.ErrorHandler(err)
+	}
+}
@@ -0,0 +1,370 @@
+package walbroker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lancer-kit/uwe/v3"
+)
+
+type order struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+const kindOrder uwe.MessageKind = 1
+
+// startBroker opens the broker in the directory and returns the function that stops it.
+func startBroker(t *testing.T, dir string) (*Broker, func()) {
+	t.Helper()
+
+	broker := New(Config{Dir: dir, ErrorHandler: func(err error) { t.Error(err) }})
+	broker.RegisterCodec(kindOrder, JSONCodec(order{}))
+	if err := broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		broker.Serve(ctx)
+		close(done)
+	}()
+
+	return broker, func() {
+		cancel()
+		<-done
+	}
+}
+
+func receive(t *testing.T, mailbox uwe.Mailbox) *uwe.Message {
+	t.Helper()
+
+	select {
+	case msg := <-mailbox.Messages():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("message was not received")
+		return nil
+	}
+}
+
+func expectNothing(t *testing.T, mailbox uwe.Mailbox) {
+	t.Helper()
+
+	select {
+	case msg := <-mailbox.Messages():
+		t.Fatalf("unexpected message: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// waitPending waits until the acknowledgements are routed and the worker has the expected number of unacknowledged messages.
+func waitPending(t *testing.T, broker *Broker, name uwe.WorkerName, expected int) {
+	t.Helper()
+
+	var pending int
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		broker.mutex.Lock()
+		pending = len(broker.queue(name).entries)
+		broker.mutex.Unlock()
+		if pending == expected {
+			return
+		}
+	}
+	t.Fatalf("pending messages of the %s(%d) != %d", name, pending, expected)
+}
+
+func TestBroker_Redelivery(t *testing.T) {
+	dir := t.TempDir()
+
+	broker, stop := startBroker(t, dir)
+	mailbox := broker.AddWorker("consumer")
+	bus := broker.DefaultBus()
+
+	bus.SendWithKind("consumer", kindOrder, order{ID: 1, Title: "first"})
+	bus.SendWithKind("consumer", kindOrder, order{ID: 2, Title: "second"})
+	bus.Send("consumer", "plain")
+
+	first := receive(t, mailbox)
+	if data, ok := first.Data.(order); !ok || data.ID != 1 || first.Meta[MetaRedelivered] != "" {
+		t.Fatalf("unexpected message: %+v", first)
+	}
+	Ack(mailbox, first)
+
+	second := receive(t, mailbox)
+	if data, ok := second.Data.(order); !ok || data.ID != 2 {
+		t.Fatalf("unexpected message: %+v", second)
+	}
+
+	// the worker restarts without the acknowledgement of the second message
+	waitPending(t, broker, "consumer", 2)
+	mailbox = broker.AddWorker("consumer")
+	second = receive(t, mailbox)
+	if data, ok := second.Data.(order); !ok || data.ID != 2 || second.Meta[MetaRedelivered] != "true" {
+		t.Fatalf("unexpected redelivered message: %+v", second)
+	}
+	Ack(mailbox, second)
+	waitPending(t, broker, "consumer", 1)
+	stop()
+
+	// the process restarts, only the unacknowledged message is restored
+	broker, stop = startBroker(t, dir)
+	defer stop()
+
+	mailbox = broker.AddWorker("consumer")
+	plain := receive(t, mailbox)
+	if plain.Data != "plain" || plain.Sender != "" || plain.Target != "consumer" || plain.Meta[MetaRedelivered] != "true" {
+		t.Fatalf("unexpected restored message: %+v", plain)
+	}
+	expectNothing(t, mailbox)
+
+	if depths := broker.MailboxDepths(); depths["consumer"] != 0 {
+		t.Errorf("unexpected depths: %v", depths)
+	}
+}
+
+func TestBroker_Compact(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, LogFileName)
+
+	broker, stop := startBroker(t, dir)
+	mailbox := broker.AddWorker("consumer")
+	broker.AddWorker("other")
+	bus := broker.DefaultBus()
+
+	for i := 0; i < 10; i++ {
+		bus.SendWithKind("consumer", kindOrder, order{ID: i})
+	}
+	bus.SendWithKind(uwe.TargetBroadcast, kindOrder, order{ID: 100})
+
+	for i := 0; i < 10; i++ {
+		Ack(mailbox, receive(t, mailbox))
+	}
+	broadcast := receive(t, mailbox)
+	if broadcast.Target != uwe.TargetBroadcast {
+		t.Errorf("target of the broadcast message(%s) != %s", broadcast.Target, uwe.TargetBroadcast)
+	}
+
+	waitPending(t, broker, "consumer", 1)
+
+	before, _ := os.Stat(path)
+	if err := broker.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("log was not compacted: %d >= %d", after.Size(), before.Size())
+	}
+	stop()
+
+	// the torn tail of the interrupted write is dropped
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.Write([]byte{42, 0, 0, 0, 1, 2})
+	_ = file.Close()
+
+	var truncated []error
+	broker = New(Config{Dir: dir, ErrorHandler: func(err error) { truncated = append(truncated, err) }})
+	if err = broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if len(truncated) != 1 {
+		t.Errorf("truncation was not reported: %v", truncated)
+	}
+
+	depths := broker.MailboxDepths()
+	if depths["consumer"] != 1 || depths["other"] != 1 {
+		t.Errorf("unexpected restored depths: %v", depths)
+	}
+	broker.shutdown()
+}
+
+func TestBroker_RecordTooLarge(t *testing.T) {
+	defer func(size int) { maxRecordSize = size }(maxRecordSize)
+	maxRecordSize = 256
+
+	dir := t.TempDir()
+	var reported []error
+	broker := New(Config{Dir: dir, ErrorHandler: func(err error) { reported = append(reported, err) }})
+	broker.RegisterCodec(kindOrder, JSONCodec(order{}))
+	if err := broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	broker.AddWorker("consumer")
+	broker.store(&uwe.Message{Kind: kindOrder, Data: order{ID: 1}}, []uwe.WorkerName{"consumer"}, false)
+	broker.store(&uwe.Message{Kind: kindOrder, Data: order{ID: 2, Title: strings.Repeat("x", 512)}}, []uwe.WorkerName{"consumer"}, false)
+	if len(reported) != 1 || !errors.Is(reported[0], ErrRecordTooLarge) {
+		t.Errorf("oversized message was not rejected: %v", reported)
+	}
+
+	// the log stays writable after the compaction
+	if err := broker.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	broker.store(&uwe.Message{Kind: kindOrder, Data: order{ID: 3}}, []uwe.WorkerName{"consumer"}, false)
+	broker.shutdown()
+
+	broker = New(Config{Dir: dir, ErrorHandler: func(err error) { t.Error(err) }})
+	if err := broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer broker.shutdown()
+
+	if depths := broker.MailboxDepths(); depths["consumer"] != 2 {
+		t.Errorf("unexpected restored depths: %v", depths)
+	}
+}
+
+func TestBroker_CorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, LogFileName)
+
+	broker := New(Config{Dir: dir, ErrorHandler: func(err error) { t.Error(err) }})
+	if err := broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+	broker.AddWorker("consumer")
+	for i := 0; i < 3; i++ {
+		broker.store(&uwe.Message{Data: i}, []uwe.WorkerName{"consumer"}, false)
+	}
+	broker.shutdown()
+
+	// the checksum of the first record is broken, the next records are kept
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[4] ^= 0xff
+	if err = os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var reported []error
+	broker = New(Config{Dir: dir, ErrorHandler: func(err error) { reported = append(reported, err) }})
+	if err = broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer broker.shutdown()
+
+	if len(reported) != 1 || !errors.Is(reported[0], ErrLogDamaged) || !strings.Contains(reported[0].Error(), "1 corrupted records") {
+		t.Errorf("damage was not reported: %v", reported)
+	}
+	if depths := broker.MailboxDepths(); depths["consumer"] != 2 {
+		t.Errorf("unexpected restored depths: %v", depths)
+	}
+}
+
+func TestBroker_RemoveWorker(t *testing.T) {
+	dir := t.TempDir()
+
+	broker := New(Config{Dir: dir, ErrorHandler: func(err error) { t.Error(err) }})
+	if err := broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+	broker.AddWorker("removed")
+	for i := 0; i < 3; i++ {
+		broker.store(&uwe.Message{Data: i}, []uwe.WorkerName{"removed"}, false)
+	}
+
+	broker.RemoveWorker("removed")
+	if err := broker.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if size := broker.log.size(); size != 0 {
+		t.Errorf("messages of the removed worker were kept in the log: %d bytes", size)
+	}
+	broker.shutdown()
+
+	broker = New(Config{Dir: dir, ErrorHandler: func(err error) { t.Error(err) }})
+	if err := broker.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer broker.shutdown()
+
+	if depths := broker.MailboxDepths(); len(depths) != 0 {
+		t.Errorf("unexpected restored depths: %v", depths)
+	}
+}
+
+type ackWorker struct {
+	received chan *uwe.Message
+}
+
+func (w *ackWorker) Init() error { return nil }
+
+func (w *ackWorker) Run(ctx uwe.Context) error {
+	for {
+		select {
+		case msg := <-ctx.Messages():
+			Ack(ctx, msg)
+			w.received <- msg
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+type publisherWorker struct{}
+
+func (publisherWorker) Init() error { return nil }
+
+func (publisherWorker) Run(ctx uwe.Context) error {
+	uwe.Publish(ctx, "orders.created", kindOrder, order{ID: 7})
+	<-ctx.Done()
+	return nil
+}
+
+func TestBroker_Chief(t *testing.T) {
+	worker := &ackWorker{received: make(chan *uwe.Message, 1)}
+
+	broker := New(Config{Dir: t.TempDir()})
+	broker.RegisterCodec(kindOrder, JSONCodec(order{}))
+
+	chief := uwe.NewChief()
+	chief.SetEventHandler(func(uwe.Event) {})
+	chief.UseCustomIMQBroker(broker)
+	chief.AddWorker("consumer", &subscriberWorker{ackWorker: worker})
+	chief.AddWorker("publisher", publisherWorker{}, uwe.DependsOnReady("consumer"))
+	chief.SetLocker(func() {
+		select {
+		case msg := <-worker.received:
+			if data, ok := msg.Data.(order); !ok || data.ID != 7 || msg.Topic != "orders.created" {
+				t.Errorf("unexpected message: %+v", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("published message was not received")
+		}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		chief.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("chief was not stopped in time")
+	}
+}
+
+// subscriberWorker subscribes to the topic before it signals the readiness.
+type subscriberWorker struct {
+	*ackWorker
+}
+
+func (w *subscriberWorker) Run(ctx uwe.Context) error {
+	uwe.Subscribe(ctx, "orders.*")
+	uwe.SignalReady(ctx)
+	return w.ackWorker.Run(ctx)
+}
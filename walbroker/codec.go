@@ -0,0 +1,42 @@
+package walbroker
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// Codec converts the `uwe.Message.Data` of the particular `uwe.MessageKind` to the bytes stored in the log.
+type Codec interface {
+	Encode(data interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// JSONCodec returns the `Codec` which stores the data as JSON and decodes it into the value
+// of the same type as the `sample`. If the `sample` is nil, the data is decoded into the `interface{}`,
+// so the objects are received as the `map[string]interface{}` and the numbers as the `float64`.
+// It is used for the kinds without the registered codec.
+func JSONCodec(sample interface{}) Codec {
+	return jsonCodec{typ: reflect.TypeOf(sample)}
+}
+
+type jsonCodec struct {
+	typ reflect.Type
+}
+
+func (jsonCodec) Encode(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+func (c jsonCodec) Decode(data []byte) (interface{}, error) {
+	if c.typ == nil {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		return v, err
+	}
+
+	ptr := reflect.New(c.typ)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
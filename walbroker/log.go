@@ -0,0 +1,246 @@
+package walbroker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/lancer-kit/uwe/v3"
+)
+
+// LogFileName is a name of the log file in the data directory.
+const LogFileName = "imq.wal"
+
+// maxRecordSize limits the size of the single record, the bigger length in the header means the corrupted log.
+// It is a variable to be lowered by the tests.
+var maxRecordSize = 64 << 20
+
+// ErrRecordTooLarge is returned when the encoded message exceeds the size limit of the log record.
+var ErrRecordTooLarge = errors.New("record is too large")
+
+const (
+	opPut = "put"
+	opAck = "ack"
+)
+
+// record is an entry of the log: the message put into the mailbox or the acknowledgement of it.
+type record struct {
+	Op            string            `json:"op"`
+	Seq           uint64            `json:"seq"`
+	Target        uwe.WorkerName    `json:"target,omitempty"`
+	Sender        uwe.WorkerName    `json:"sender,omitempty"`
+	Kind          uwe.MessageKind   `json:"kind,omitempty"`
+	Topic         string            `json:"topic,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"`
+	Meta          map[string]string `json:"meta,omitempty"`
+	Data          []byte            `json:"data,omitempty"`
+}
+
+// wal is an append-only log. Each record is framed by the header
+// with the length and the CRC32 checksum of the JSON payload.
+type wal struct {
+	dir    string
+	file   *os.File
+	noSync bool
+}
+
+// damage describes the invalid part of the log found by the `openWAL`.
+type damage struct {
+	// corrupted is a number of the records with the wrong checksum or payload, they are skipped.
+	corrupted int
+	// truncated is a size of the dropped tail after the last valid record.
+	truncated int64
+}
+
+func (d damage) String() string {
+	return fmt.Sprintf("%d corrupted records were skipped, %d bytes of the tail were truncated", d.corrupted, d.truncated)
+}
+
+// openWAL opens the log in the directory and reads all its records.
+// The corrupted records are skipped, the reading continues after them while the framing is valid.
+// The tail after the last valid record, e.g. after the interrupted write, is truncated.
+func openWAL(dir string, noSync bool) (w *wal, records []record, dmg damage, err error) {
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return nil, nil, dmg, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, LogFileName), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, dmg, err
+	}
+
+	records, size, corrupted, err := readRecords(file)
+	if err == nil {
+		dmg.corrupted = corrupted
+		dmg.truncated, err = truncateTail(file, size)
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, nil, damage{}, err
+	}
+
+	return &wal{dir: dir, file: file, noSync: noSync}, records, dmg, nil
+}
+
+// readRecords reads the valid records and returns the size of the log up to the end of the last valid record
+// and the number of the skipped corrupted records. The reading stops at the incomplete record
+// or at the length which exceeds the `maxRecordSize`, because the next records can not be found after it.
+func readRecords(r io.Reader) (records []record, size int64, corrupted int, err error) {
+	var (
+		offset int64
+		header [8]byte
+	)
+
+	br := bufio.NewReader(r)
+	for {
+		if _, err = io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, size, corrupted, nil
+			}
+			return nil, 0, 0, err
+		}
+
+		length := binary.LittleEndian.Uint32(header[:4])
+		if int64(length) > int64(maxRecordSize) {
+			return records, size, corrupted, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err = io.ReadFull(br, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return records, size, corrupted, nil
+			}
+			return nil, 0, 0, err
+		}
+		offset += int64(len(header)) + int64(length)
+
+		var rec record
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) ||
+			json.Unmarshal(payload, &rec) != nil {
+			corrupted++
+			continue
+		}
+
+		records = append(records, rec)
+		size = offset
+	}
+}
+
+// truncateTail drops everything after the valid records and moves the write position to the end.
+func truncateTail(file *os.File, size int64) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	truncated := info.Size() - size
+	if truncated > 0 {
+		if err = file.Truncate(size); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = file.Seek(size, io.SeekStart)
+	return truncated, err
+}
+
+// encodeRecords frames the records for the write.
+func encodeRecords(records []record) ([]byte, error) {
+	var buf []byte
+	for _, rec := range records {
+		payload, err := json.Marshal(rec)
+		if err != nil {
+			return nil, err
+		}
+		// the record which does not pass the check in the `readRecords` would truncate the log
+		if len(payload) > maxRecordSize {
+			return nil, fmt.Errorf("%w: %d bytes of the message %d", ErrRecordTooLarge, len(payload), rec.Seq)
+		}
+
+		var header [8]byte
+		binary.LittleEndian.PutUint32(header[:4], uint32(len(payload)))
+		binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+		buf = append(buf, header[:]...)
+		buf = append(buf, payload...)
+	}
+	return buf, nil
+}
+
+// append writes the records with a single write and syncs the file, unless the `noSync` is set.
+func (w *wal) append(records ...record) error {
+	buf, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	if _, err = w.file.Write(buf); err != nil {
+		return err
+	}
+	if w.noSync {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// rewrite atomically replaces the log by the passed records.
+// The new log is written through the handle which replaces the current one,
+// so the current handle stays valid until the rename succeeds.
+func (w *wal) rewrite(records []record) error {
+	buf, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(w.dir, LogFileName)
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(buf)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	syncDir(w.dir)
+
+	_ = w.file.Close()
+	w.file = file
+	return nil
+}
+
+func (w *wal) size() int64 {
+	info, err := w.file.Stat()
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+func (w *wal) close() error {
+	return w.file.Close()
+}
+
+// syncDir persists the rename of the log, it is not supported on some platforms, so the error is ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
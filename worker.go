@@ -2,13 +2,28 @@ package uwe
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/sheb-gregor/sam"
 )
 
 type WorkerName string
 
+var (
+	// ErrRestartLimitExceeded is returned when the worker
+	// was restarted more times than the `RestartLimit` allows.
+	ErrRestartLimitExceeded = errors.New("restart limit exceeded")
+	// ErrWorkerNotExist is returned when the worker is not registered in the `Chief`.
+	ErrWorkerNotExist = errors.New("not exist")
+	// ErrWorkerNotRunning is returned when the worker must be running to perform the action.
+	ErrWorkerNotRunning = errors.New("worker is not running")
+	// ErrWorkerRunning is returned when the worker must be stopped to perform the action.
+	ErrWorkerRunning = errors.New("worker is already running")
+)
+
 // Worker is an interface for async workers
 // which launches and manages by the `Chief`.
 type Worker interface {
@@ -25,13 +40,196 @@ type WorkerWithInit interface {
 	Init() error
 }
 
+// WorkerWithHealthCheck is a worker which can report its health while running,
+// for example, a consumer can check that the connection to the broker is still alive.
+// The `Chief` polls the `HealthCheck` on the interval from the `HealthCheckOption`.
+// A failed check moves the worker into the `WStateDegraded` state,
+// the next successful check moves it back.
+type WorkerWithHealthCheck interface {
+	Worker
+	// HealthCheck returns an error if the worker is unhealthy.
+	// The check must respect the context deadline.
+	HealthCheck(ctx context.Context) error
+}
+
+// Reloadable is a worker which can apply the new configuration without the restart.
+// The `Chief` calls the `Reload` of the running worker on the SIGHUP signal,
+// the `ReloadAction` command or the `(Chief).Reload()` call.
+type Reloadable interface {
+	Worker
+	// Reload applies the config returned by the `ConfigLoader` of the `Chief`.
+	// The error is reported as the event, the worker keeps running.
+	Reload(ctx context.Context, config interface{}) error
+}
+
 // workerRO worker runtime object, hold worker instance, state and communication chanel
 type workerRO struct {
 	sam.StateMachine
 
+	name        WorkerName
 	worker      Worker
 	restartMode RestartOption
+	backoff     *backoff
 	canceler    context.CancelFunc
+	done        chan struct{}
+	group       GroupName
+	run         *workerRun
+	dependsOn   []WorkerName
+	readyDeps   []WorkerName
+	stopTimeout time.Duration
+	goroutine   uint64
+	observer    Observer
+	healthCheck *HealthCheckOption
+	watchdog    time.Duration
+	health      healthStatus
+	reloadMode  ReloadOption
+	mailbox     *MailboxOption
+	// removing is set while the worker is stopped to be removed, it can not be started again.
+	removing bool
+
+	restartLimit  *RestartLimit
+	restarts      []time.Time
+	restartsTotal int
+
+	startedAt      time.Time
+	lastTransition time.Time
+	panics         int
+	lastError      string
+	lastPanicStack string
+}
+
+// workerRun is a handle of the single `Worker.Run` execution.
+type workerRun struct {
+	ctx    Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	// restart is set when the run was interrupted to restart the worker,
+	// reInit and reason define how and why the worker will be restarted.
+	restart bool
+	reInit  bool
+	reason  string
+	// watchers are the health checker and the watchdog of the run.
+	watchers sync.WaitGroup
+	// beats receives the worker heartbeats, if the `Watchdog` is set.
+	beats chan struct{}
+}
+
+// healthStatus holds the results of the worker health checks.
+type healthStatus struct {
+	failures  int
+	lastError string
+	lastCheck time.Time
+	// healthyState is a state to which the worker returns when it recovers.
+	healthyState sam.State
+}
+
+// interrupt cancels the run to restart the worker.
+// It returns `false` if the run is already finished.
+func (r *workerRun) interrupt(reInit bool, reason string) bool {
+	select {
+	case <-r.done:
+		return false
+	default:
+	}
+
+	r.restart = true
+	r.reInit = reInit
+	r.reason = reason
+	r.cancel()
+	return true
+}
+
+// launched reports whether the worker goroutine is launched and not finished yet.
+func (w *workerRO) launched() bool {
+	if w.done == nil {
+		return false
+	}
+
+	select {
+	case <-w.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// goTo moves the worker to the state and records the time of the transition.
+func (w *workerRO) goTo(state sam.State) error {
+	prev := w.State()
+	if err := w.GoTo(state); err != nil {
+		return err
+	}
+
+	w.lastTransition = time.Now()
+	if state == WStateRun && prev != WStateDegraded {
+		w.startedAt = w.lastTransition
+	}
+
+	if w.observer != nil {
+		w.observer.WorkerStateChanged(w.name, prev, state)
+	}
+	return nil
+}
+
+// registerRestart accounts a new restart of the worker
+// and returns `false` if the restart exceeds the `RestartLimit`.
+func (w *workerRO) registerRestart(now time.Time) bool {
+	w.restartsTotal++
+	if w.restartLimit == nil {
+		return true
+	}
+
+	w.restarts = append(w.restarts, now)
+	w.restarts = w.restartsInWindow(now)
+	return len(w.restarts) <= w.restartLimit.MaxRestarts
+}
+
+// restartsInWindow returns the restarts that happened within the `RestartLimit.Window`.
+func (w *workerRO) restartsInWindow(now time.Time) []time.Time {
+	if w.restartLimit == nil {
+		return nil
+	}
+
+	since := now.Add(-w.restartLimit.Window)
+	for i, t := range w.restarts {
+		if t.After(since) {
+			return w.restarts[i:]
+		}
+	}
+	return w.restarts[:0]
+}
+
+// info returns the runtime details of the worker.
+func (w *workerRO) info(now time.Time) WorkerInfo {
+	info := WorkerInfo{
+		State:          w.State(),
+		StartedAt:      w.startedAt,
+		LastTransition: w.lastTransition,
+		Restarts:       RestartStats{Total: w.restartsTotal},
+		Panics:         w.panics,
+		LastError:      w.lastError,
+		LastPanicStack: w.lastPanicStack,
+	}
+	if !w.startedAt.IsZero() && IsRunning(info.State) {
+		info.Uptime = now.Sub(w.startedAt)
+	}
+
+	if w.restartLimit != nil {
+		info.Restarts.InWindow = len(w.restartsInWindow(now))
+		info.Restarts.MaxRestarts = w.restartLimit.MaxRestarts
+		info.Restarts.Window = w.restartLimit.Window
+	}
+
+	if _, ok := w.worker.(WorkerWithHealthCheck); ok {
+		info.Health = &HealthInfo{
+			Healthy:             w.State() != WStateDegraded,
+			Reason:              w.health.lastError,
+			ConsecutiveFailures: w.health.failures,
+			LastCheck:           w.health.lastCheck,
+		}
+	}
+
+	return info
 }
 
 const (
@@ -39,24 +237,37 @@ const (
 	WStateNew         sam.State = "New"
 	WStateInitialized sam.State = "Initialized"
 	WStateRun         sam.State = "Run"
+	WStateReady       sam.State = "Ready"
+	WStateDegraded    sam.State = "Degraded"
 	WStateStopped     sam.State = "Stopped"
 	WStateFailed      sam.State = "Failed"
 )
 
+// IsRunning returns `true` if the worker in this state is running:
+// it is started, but may be not ready yet or degraded.
+func IsRunning(state sam.State) bool {
+	return state == WStateRun || state == WStateReady || state == WStateDegraded
+}
+
 // newWorkerSM returns filled state machine of the worker lifecycle
 //
-// (*) -> [New] -> [Initialized] -> [Run] -> [Stopped]
+// (*) -> [New] -> [Initialized] -> [Run] -> [Ready] -> [Stopped]
 //
-//	|             |           |
-//	|             |           ↓
-//	|--------------------> [Failed]
+//	|             |           |         |
+//	|             |           ↓         ↓
+//	|--------------------> [Failed] <----
 //							(from [Failed] state can get back
 //							 to [Initialized] or to [Run])
+//
+// [Run] and [Ready] move to [Degraded] on the failed health check and back on the successful one,
+// from [Degraded] the worker can also move to [Stopped] or [Failed].
 func newWorkerSM() (sam.StateMachine, error) {
 	workerSM, err := sam.NewStateMachine().
 		AddTransitions(WStateNew, WStateInitialized, WStateFailed).
 		AddTransitions(WStateInitialized, WStateRun, WStateFailed).
-		AddTransitions(WStateRun, WStateStopped, WStateFailed).
+		AddTransitions(WStateRun, WStateReady, WStateDegraded, WStateStopped, WStateFailed).
+		AddTransitions(WStateReady, WStateDegraded, WStateStopped, WStateFailed).
+		AddTransitions(WStateDegraded, WStateRun, WStateReady, WStateStopped, WStateFailed).
 		AddTransitions(WStateFailed, WStateInitialized, WStateRun).
 		Finalize(WStateStopped)
 	if err != nil || workerSM == nil {
@@ -1,5 +1,10 @@
 package uwe
 
+import (
+	"math/rand"
+	"time"
+)
+
 type WorkerOpts interface {
 	thisIsOption()
 }
@@ -44,3 +49,210 @@ const (
 	// in case of panic or exit with error.
 	RestartAndReInit = RestartOnFail | RestartOnError | RestartWithReInit
 )
+
+// BackoffOption configures the delay between restarts of the worker
+// which uses `RestartOnFail` and/or `RestartOnError` strategies.
+// Without this option the worker is restarted immediately.
+type BackoffOption struct {
+	// InitialDelay is a delay before the first restart.
+	InitialDelay time.Duration
+	// Multiplier is a factor by which the delay grows after each restart.
+	// Values less than 1 are treated as 1 (constant delay).
+	Multiplier float64
+	// MaxDelay is an upper limit of the delay. Zero means no limit.
+	MaxDelay time.Duration
+	// Jitter is a fraction of the delay in range [0, 1]
+	// by which the delay is randomly increased or decreased.
+	Jitter float64
+	// ResetAfter is a duration of the stable run, after which
+	// the delay is reset back to the InitialDelay. Zero disables the reset.
+	ResetAfter time.Duration
+}
+
+func (BackoffOption) thisIsOption() {}
+
+// DefaultBackoff returns the `BackoffOption` with reasonable defaults:
+// delay starts from 100ms, doubles on each restart up to 30s with 20% jitter
+// and resets after a minute of the stable run.
+func DefaultBackoff() BackoffOption {
+	return BackoffOption{
+		InitialDelay: 100 * time.Millisecond,
+		Multiplier:   2,
+		MaxDelay:     30 * time.Second,
+		Jitter:       0.2,
+		ResetAfter:   time.Minute,
+	}
+}
+
+// backoff holds the runtime state of the worker restart delays.
+type backoff struct {
+	opt     BackoffOption
+	attempt int
+	delay   time.Duration
+}
+
+func newBackoff(opt BackoffOption) *backoff {
+	if opt.Multiplier < 1 {
+		opt.Multiplier = 1
+	}
+	if opt.Jitter < 0 {
+		opt.Jitter = 0
+	}
+	if opt.Jitter > 1 {
+		opt.Jitter = 1
+	}
+
+	return &backoff{opt: opt}
+}
+
+// next returns the number of the restart attempt and the delay before it.
+// The `uptime` is a duration of the last worker run.
+func (b *backoff) next(uptime time.Duration) (int, time.Duration) {
+	if b.opt.ResetAfter > 0 && uptime >= b.opt.ResetAfter {
+		b.attempt = 0
+		b.delay = 0
+	}
+
+	switch b.attempt {
+	case 0:
+		b.delay = b.opt.InitialDelay
+	default:
+		b.delay = time.Duration(float64(b.delay) * b.opt.Multiplier)
+	}
+
+	if b.opt.MaxDelay > 0 && b.delay > b.opt.MaxDelay {
+		b.delay = b.opt.MaxDelay
+	}
+	b.attempt++
+
+	delay := b.delay
+	if b.opt.Jitter > 0 && delay > 0 {
+		// nolint: gosec
+		delta := (rand.Float64()*2 - 1) * b.opt.Jitter * float64(delay)
+		delay += time.Duration(delta)
+	}
+
+	return b.attempt, delay
+}
+
+// RestartLimit limits the intensity of the worker restarts:
+// the worker can be restarted no more than `MaxRestarts` times within the sliding `Window`.
+// When the limit is exceeded, the worker stays in the `WStateFailed` state and will not be restarted,
+// or, if `Escalate` is set, the whole `Chief` shuts down with a fatal event.
+type RestartLimit struct {
+	// MaxRestarts is a maximum number of restarts within the Window.
+	MaxRestarts int
+	// Window is a duration of the sliding time window.
+	Window time.Duration
+	// Escalate enables the shutdown of the whole `Chief`
+	// when the worker exceeds the limit.
+	Escalate bool
+}
+
+func (RestartLimit) thisIsOption() {}
+
+// GroupName is a name of the group of tightly coupled workers,
+// that are supervised together according to the group `SupervisionStrategy`.
+// Passed as a worker option, it adds the worker to the group.
+// The order of the group members is the order in which they were added to the `Chief`.
+type GroupName string
+
+func (GroupName) thisIsOption() {}
+
+// SupervisionStrategy defines how the failure of one group member affects other members.
+// The strategy is applied only when the failed worker is going to be restarted
+// according to its `RestartOption`.
+type SupervisionStrategy int
+
+const (
+	// OneForOne is a default strategy, each worker is restarted on its own.
+	OneForOne SupervisionStrategy = iota
+	// OneForAll strategy restarts all members of the group when one of them fails.
+	OneForAll
+	// RestForOne strategy restarts the failed member
+	// and all members which were added to the group after it.
+	RestForOne
+)
+
+func (s SupervisionStrategy) String() string {
+	switch s {
+	case OneForAll:
+		return "one-for-all"
+	case RestForOne:
+		return "rest-for-one"
+	default:
+		return "one-for-one"
+	}
+}
+
+// Dependencies is a list of workers that must be started before the worker.
+// The worker is initialized only after all its dependencies reach the `WStateRun` state.
+// On shutdown, the worker is stopped and awaited before its dependencies.
+type Dependencies []WorkerName
+
+func (Dependencies) thisIsOption() {}
+
+// DependsOn returns the option that declares the dependencies of the worker.
+func DependsOn(names ...WorkerName) Dependencies {
+	return names
+}
+
+// ReadyDependencies is a list of workers that must signal the readiness before the worker is started.
+// It is the same as the `Dependencies`, but the worker is initialized only after all these dependencies
+// reach the `WStateReady` state, so they must call the `SignalReady`.
+type ReadyDependencies []WorkerName
+
+func (ReadyDependencies) thisIsOption() {}
+
+// DependsOnReady returns the option that declares the dependencies of the worker which must be ready.
+func DependsOnReady(names ...WorkerName) ReadyDependencies {
+	return names
+}
+
+// StopTimeout is a duration that the `Chief` waits for the worker to finish after the stop signal.
+// If the worker does not finish in time, the `Chief` emits an event with the stack of the worker
+// goroutine and moves on to the remaining workers. By default, the `ForceStopTimeout` of the `Chief` is used,
+// which also remains the upper limit of the whole shutdown.
+type StopTimeout time.Duration
+
+func (StopTimeout) thisIsOption() {}
+
+// DefaultHealthCheckInterval is an interval of the health checks,
+// if it is not set by the `HealthCheckOption`.
+const DefaultHealthCheckInterval = 10 * time.Second
+
+// HealthCheckOption configures the polling of the worker which implements `WorkerWithHealthCheck`.
+// Without this option the worker is checked every `DefaultHealthCheckInterval`.
+type HealthCheckOption struct {
+	// Interval is a duration between the checks.
+	Interval time.Duration
+	// Timeout is a deadline of the single check. Zero means the Interval is used.
+	Timeout time.Duration
+	// RestartAfter is a number of consecutive failed checks after which the worker is restarted.
+	// The worker is re-initialized if it uses `RestartWithReInit`. Zero disables the restart.
+	RestartAfter int
+}
+
+func (HealthCheckOption) thisIsOption() {}
+
+// Watchdog is an interval within which the running worker must call the `Heartbeat(ctx)`.
+// If the worker misses the deadline, the `Chief` emits an event with the stack of the worker goroutine
+// and restarts the worker, if its `RestartOption` allows, otherwise the run is canceled.
+// For the `StopAppOnFail` worker, the whole `Chief` is stopped. The deadline is counted from the start of each run.
+type Watchdog time.Duration
+
+func (Watchdog) thisIsOption() {}
+
+// ReloadOption defines how the running worker, which does not implement `Reloadable`,
+// reacts to the reload of the `Chief`.
+type ReloadOption int
+
+func (ReloadOption) thisIsOption() {}
+
+const (
+	// IgnoreOnReload is a default option, the worker keeps running as is.
+	IgnoreOnReload ReloadOption = iota
+	// ReInitOnReload restarts the worker with the re-initialization,
+	// so the worker can read the new configuration in the `Init()`.
+	ReInitOnReload
+)
@@ -0,0 +1,48 @@
+package uwe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	b := newBackoff(BackoffOption{
+		InitialDelay: 10 * time.Millisecond,
+		Multiplier:   2,
+		MaxDelay:     50 * time.Millisecond,
+		ResetAfter:   time.Second,
+	})
+
+	expected := []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	}
+	for i, exp := range expected {
+		attempt, delay := b.next(0)
+		if attempt != i+1 {
+			t.Errorf("attempt(%d) != %d", attempt, i+1)
+		}
+		if delay != exp {
+			t.Errorf("attempt %d: delay(%s) != %s", attempt, delay, exp)
+		}
+	}
+
+	attempt, delay := b.next(2 * time.Second)
+	if attempt != 1 || delay != 10*time.Millisecond {
+		t.Errorf("backoff was not reset after stable run: attempt(%d) delay(%s)", attempt, delay)
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	b := newBackoff(BackoffOption{InitialDelay: 100 * time.Millisecond, Jitter: 0.5})
+
+	for i := 0; i < 100; i++ {
+		_, delay := b.next(0)
+		if delay < 50*time.Millisecond || delay > 150*time.Millisecond {
+			t.Fatalf("delay(%s) is out of jitter range", delay)
+		}
+	}
+}